@@ -0,0 +1,89 @@
+package main
+
+import (
+	"github.com/kevinwmiller/nesgo/nes/apu"
+	"github.com/kevinwmiller/nesgo/nes/audio"
+	"github.com/kevinwmiller/nesgo/nes/bus"
+	"github.com/kevinwmiller/nesgo/nes/cartridge"
+	"github.com/kevinwmiller/nesgo/nes/clock"
+	"github.com/kevinwmiller/nesgo/nes/cpu6502"
+	"github.com/kevinwmiller/nesgo/nes/ppu"
+)
+
+// console is the hardware of the system wired together around a cartridge
+type console struct {
+	region clock.Region
+	cpu    *cpu6502.CPU
+	video  *ppu.PPU
+	sound  *apu.APU
+	cart   *cartridge.Cartridge
+	clock  *clock.Clock
+}
+
+// newConsole connects the CPU, PPU and APU to a cartridge and registers them with a master clock for the region the
+// cartridge header asks for
+func newConsole(cart *cartridge.Cartridge) *console {
+	region := regionFor(cart.Timing)
+	c := &console{
+		region: region,
+		cpu:    cpu6502.NewCPU(),
+		video:  ppu.New(),
+		sound:  apu.New(),
+		cart:   cart,
+		clock:  clock.New(region.MasterFrequency()),
+	}
+	c.video.SetRegion(region)
+	c.sound.SetRegion(region)
+	c.video.ConnectBus(cart.PPU())
+	c.video.ConnectNMI(c.cpu.SetNMI)
+	c.cpu.SetPPUPosition(c.video.Position)
+	c.sound.ConnectIRQ(func(asserted bool) {
+		c.cpu.SetIRQ(cpu6502.IRQFrameCounter, asserted)
+	}, func(asserted bool) {
+		c.cpu.SetIRQ(cpu6502.IRQDMC, asserted)
+	})
+
+	cpuBus := bus.New()
+	cpuBus.Map(bus.CartridgeStart, bus.CartridgeEnd, 0, cart)
+	cpuBus.Map(bus.PPUStart, bus.PPUEnd, bus.PPURegisters, c.video)
+	cpuBus.Map(bus.IOStart, bus.IOEnd, 0, c.sound)
+	cpuBus.Map(bus.OAMDMA, bus.OAMDMA, 0, c.cpu.OAMDMA())
+	cpuBus.Observe(bus.PPUStart, bus.PPUEnd, bus.PPURegisters, cart)
+	c.cpu.ConnectBus(cpuBus)
+	c.sound.ConnectBus(cpuBus, c.cpu.Stall)
+	cart.ConnectIRQ(func(asserted bool) {
+		c.cpu.SetIRQ(cpu6502.IRQMapper, asserted)
+	})
+	if chip := cart.Audio(); chip != nil {
+		c.sound.ConnectExpansion(chip)
+	}
+
+	c.clock.RegisterComponent(c.cpu, region.CPUDivider())
+	c.clock.RegisterComponent(cart, region.CPUDivider())
+	c.clock.RegisterComponent(c.video, region.PPUDivider())
+	c.clock.RegisterComponent(c.sound, region.CPUDivider())
+	return c
+}
+
+// regionFor returns the console region to run a ROM with the given timing on. Multi-region ROMs run on NTSC
+func regionFor(timing cartridge.Timing) clock.Region {
+	switch timing {
+	case cartridge.PAL:
+		return clock.PAL
+	case cartridge.Dendy:
+		return clock.Dendy
+	}
+	return clock.NTSC
+}
+
+// record sends the audio through the console's output filters to sink, resampled to rate Hz
+func (c *console) record(rate int, sink audio.Sink) {
+	resampler := audio.NewResampler(c.region.CPUFrequency(), float64(rate), audio.NewFilters(float64(rate), sink))
+	c.sound.OnSample(resampler.WriteSample)
+}
+
+// powerUp puts the CPU and APU in their power up state and starts the program at the reset vector
+func (c *console) powerUp() {
+	c.cpu.Reset()
+	c.sound.PowerUp()
+}
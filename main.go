@@ -1,27 +1,278 @@
 package main
 
 import (
-	"github.com/kevinwmiller/nesgo/nes/bus"
-	"github.com/kevinwmiller/nesgo/nes/clock"
-	"github.com/kevinwmiller/nesgo/nes/cpu6502"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kevinwmiller/nesgo/nes/audio"
+	"github.com/kevinwmiller/nesgo/nes/cartridge"
+	"github.com/kevinwmiller/nesgo/nes/cpu6502"
+	"github.com/kevinwmiller/nesgo/nes/palette"
+	"github.com/kevinwmiller/nesgo/nes/ppu"
+	"github.com/kevinwmiller/nesgo/nes/screenshot"
 )
 
 const windowWidth = 1024
 const windowHeight = 720
 
+// saveInterval is how often battery saves are flushed
+const saveInterval = time.Second
+
 func init() {
 	runtime.LockOSThread()
 }
 
 func main() {
-	cpu := cpu6502.NewCPU()
-	bus := bus.Bus{}
-	cpu.ConnectBus(&bus)
-	clock := clock.Clock{}
-	clock.RegisterComponent(cpu, 3)
-
-	// cpu.Dump()
-	cpu.Reset()
-	clock.Start()
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <rom.nes | music.nsf | music.nsfe>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	saveDir := flag.String("savedir", "", "directory to keep battery saves in instead of next to the ROM")
+	frames := flag.Uint64("frames", 0, "stop after this many frames instead of running until interrupted")
+	screenshotPath := flag.String("screenshot", "", "write the last frame to this PNG file when the emulator stops")
+	dumpPattern := flag.String("dump", "", "write frames to a numbered PNG sequence named by this pattern, such as frames/%06d.png")
+	dumpEvery := flag.Uint64("dump-every", 1, "only dump every nth frame")
+	dumpRange := flag.String("dump-range", "", "only dump frames first-last, inclusive, then stop")
+	palettePath := flag.String("palette", "", "64 or 512 colour .pal file to use instead of the built in palette")
+	wavPath := flag.String("wav", "", "record the audio to this WAV file")
+	sampleRate := flag.Int("samplerate", audio.Rate48000, "sample rate of recorded audio in Hz, such as 44100 or 48000")
+	track := flag.Int("track", 0, "NSF track to record with -wav, counting from 1. Defaults to the file's starting track")
+	soundtrackDir := flag.String("soundtrack", "", "record every NSF track in playlist order to WAV files in this directory")
+	trackLength := flag.Duration("length", 3*time.Minute, "how long to record NSF tracks that don't give their own length")
+	trackFade := flag.Duration("fade", 5*time.Second, "how long to fade out NSF tracks that don't give their own fade")
+	regionName := flag.String("region", "auto", "console timing: auto to follow the ROM header, ntsc, pal or dendy")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	timing, override, err := parseRegion(*regionName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	interrupt := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(interrupt)
+	}()
+
+	if isNSF(flag.Arg(0)) {
+		n, err := cartridge.LoadNSF(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if override {
+			n.Timing = timing
+		}
+		r := nsfRecorder{
+			nsf:       n,
+			rate:      *sampleRate,
+			length:    *trackLength,
+			fade:      *trackFade,
+			interrupt: interrupt,
+		}
+		switch {
+		case *soundtrackDir != "":
+			err = r.recordSoundtrack(*soundtrackDir)
+		case *wavPath != "":
+			song := n.StartSong
+			if *track != 0 {
+				song = *track - 1
+			}
+			if song < 0 || song >= n.Songs {
+				fmt.Fprintf(os.Stderr, "track %d is out of range, the file has %d tracks\n", *track, n.Songs)
+				os.Exit(2)
+			}
+			err = r.recordTrack(song, *wavPath)
+		default:
+			fmt.Fprintln(os.Stderr, "NSF files can only be recorded, use -wav or -soundtrack")
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	pal := palette.Default()
+	if *palettePath != "" {
+		var err error
+		if pal, err = palette.Load(*palettePath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	var dumper *screenshot.Dumper
+	if *dumpPattern != "" {
+		dumper = screenshot.NewDumper(*dumpPattern, pal)
+		dumper.Every(*dumpEvery)
+		if *dumpRange != "" {
+			first, last, err := parseRange(*dumpRange)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			dumper.Range(first, last)
+		}
+	}
+
+	cart, err := cartridge.Load(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if override {
+		cart.Timing = timing
+	}
+
+	nes := newConsole(cart)
+	nes.cpu.SetJamBehavior(cpu6502.ErrorOnJam)
+
+	var saver *cartridge.Saver
+	if cart.HasBattery() {
+		savePath := cartridge.SavePath(flag.Arg(0), *saveDir)
+		if err := cart.LoadSave(savePath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		saver = cartridge.NewSaver(cart, savePath)
+		nes.clock.RegisterComponent(saver, nes.clock.Cycles(saveInterval))
+	}
+
+	nes.video.OnFrame(func(number uint64, frame *ppu.Frame) {
+		if nes.cpu.Err() != nil {
+			nes.clock.Stop()
+		}
+		// Stop rather than keep playing when progress can no longer be saved
+		if saver != nil && saver.Err() != nil {
+			nes.clock.Stop()
+		}
+		if dumper != nil {
+			dumper.Frame(number, frame)
+			if dumper.Err() != nil || (*dumpRange != "" && dumper.Done(number)) {
+				nes.clock.Stop()
+			}
+		}
+		if *frames != 0 && number+1 >= *frames {
+			nes.clock.Stop()
+		}
+		if interrupted(interrupt) {
+			nes.clock.Stop()
+		}
+	})
+
+	var wav *audio.WAVWriter
+	if *wavPath != "" {
+		f, err := os.Create(*wavPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		if wav, err = audio.NewWAVWriter(f, *sampleRate); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		nes.record(*sampleRate, wav)
+	}
+
+	nes.powerUp()
+	nes.clock.Start()
+
+	if saver != nil {
+		err := saver.Flush()
+		if err == nil {
+			err = saver.Err()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if wav != nil {
+		if err := wav.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if dumper != nil && dumper.Err() != nil {
+		fmt.Fprintln(os.Stderr, dumper.Err())
+		os.Exit(1)
+	}
+	if err := nes.cpu.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *screenshotPath != "" {
+		if err := screenshot.Save(*screenshotPath, nes.video.Frame(), pal); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// interrupted returns true once the emulator has been asked to stop by a signal
+func interrupted(interrupt <-chan struct{}) bool {
+	select {
+	case <-interrupt:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRange parses a frame range written as first-last
+func parseRange(s string) (first, last uint64, err error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("frame range %q is not first-last", s)
+	}
+	if first, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("frame range %q: %w", s, err)
+	}
+	if last, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("frame range %q: %w", s, err)
+	}
+	if last < first {
+		return 0, 0, fmt.Errorf("frame range %q ends before it starts", s)
+	}
+	return first, last, nil
+}
+
+// parseRegion parses the -region flag. override is false if the ROM's own timing should be used
+func parseRegion(name string) (timing cartridge.Timing, override bool, err error) {
+	switch strings.ToLower(name) {
+	case "auto":
+		return cartridge.NTSC, false, nil
+	case "ntsc":
+		return cartridge.NTSC, true, nil
+	case "pal":
+		return cartridge.PAL, true, nil
+	case "dendy":
+		return cartridge.Dendy, true, nil
+	}
+	return cartridge.NTSC, false, fmt.Errorf("unknown region %q, use auto, ntsc, pal or dendy", name)
+}
+
+// isNSF returns true if path names an NSF or NSFe file rather than a ROM
+func isNSF(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".nsf", ".nsfe":
+		return true
+	}
+	return false
 }
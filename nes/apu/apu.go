@@ -0,0 +1,278 @@
+// Package apu implements the audio processing unit of the 2A03.
+// http://wiki.nesdev.com/w/index.php/APU
+package apu
+
+import (
+	"github.com/kevinwmiller/nesgo/nes/bus"
+	"github.com/kevinwmiller/nesgo/nes/clock"
+)
+
+// Registers, as offsets from bus.IOStart. Each channel has 4 registers
+const (
+	pulse1Registers   = 0x00
+	pulse2Registers   = 0x04
+	triangleRegisters = 0x08
+	noiseRegisters    = 0x0C
+	dmcRegisters      = 0x10
+	channelsEnd       = 0x13
+	statusRegister    = 0x15
+	frameRegister     = 0x17
+)
+
+// $4015 bits
+const (
+	statusPulse1   uint8 = 0x01
+	statusPulse2   uint8 = 0x02
+	statusTriangle uint8 = 0x04
+	statusNoise    uint8 = 0x08
+	statusDMC      uint8 = 0x10
+	statusFrameIRQ uint8 = 0x40
+	statusDMCIRQ   uint8 = 0x80
+	// Bit 5 of $4015 is not driven and reads back open bus
+	statusDriven uint8 = 0xDF
+)
+
+// Expansion is a sound chip on the cartridge whose output is mixed with the APU's through a pin on the cartridge
+// connector. Mappers with their own sound hardware implement it. http://wiki.nesdev.com/w/index.php/Expansion_audio
+type Expansion interface {
+	// Sample returns the chip's current output, normalised to -1 to 1. Chips are clocked by the mapper's Tick
+	Sample() float32
+	// Volume returns the level of a full scale sample relative to the APU's 0 to 1 output
+	Volume() float32
+}
+
+// IRQLine is called when the APU asserts or releases one of its interrupts
+type IRQLine func(asserted bool)
+
+// APU is the 2A03's sound generator: two pulse channels, a triangle, noise and the delta modulation channel, driven by
+// the frame sequencer. It is clocked once per CPU cycle and hands every sample it produces to the function set with
+// OnSample, so it runs without any audio device
+type APU struct {
+	pulse1   pulse
+	pulse2   pulse
+	triangle triangle
+	noise    noise
+	dmc      dmc
+	frame    frameCounter
+
+	cycle uint64
+
+	bus      bus.Device
+	stall    func(cycles int)
+	frameIRQ IRQLine
+	dmcIRQ   IRQLine
+	// The levels last driven on the IRQ lines
+	frameIRQLine bool
+	dmcIRQLine   bool
+
+	expansion []Expansion
+
+	onSample func(sample float32)
+}
+
+// New returns an NTSC APU in its power up state
+func New() *APU {
+	return &APU{
+		pulse1: pulse{onesComplement: true},
+		noise:  newNoise(),
+		dmc:    newDMC(),
+		frame:  frameCounter{sequence: &ntscFrameSequence},
+	}
+}
+
+// SetRegion switches the noise periods, DMC rates and frame sequencer timing to those of a region. Dendy clones use
+// the NTSC timing. The APU produces a sample every CPU cycle, so its sample rate is region.CPUFrequency()
+func (a *APU) SetRegion(region clock.Region) {
+	a.noise.periods = &ntscNoisePeriods
+	a.dmc.rates = &ntscDMCRates
+	a.frame.sequence = &ntscFrameSequence
+	if region == clock.PAL {
+		a.noise.periods = &palNoisePeriods
+		a.dmc.rates = &palDMCRates
+		a.frame.sequence = &palFrameSequence
+	}
+}
+
+// ConnectBus connects the bus the DMC fetches samples from. stall is called with the number of CPU cycles each fetch
+// steals
+func (a *APU) ConnectBus(device bus.Device, stall func(cycles int)) {
+	a.bus = device
+	a.stall = stall
+}
+
+// ConnectIRQ sets the functions called when the frame sequencer and DMC interrupts are asserted or released
+func (a *APU) ConnectIRQ(frame, dmc IRQLine) {
+	a.frameIRQ = frame
+	a.dmcIRQ = dmc
+}
+
+// ConnectExpansion mixes a cartridge sound chip into the output
+func (a *APU) ConnectExpansion(chip Expansion) {
+	a.expansion = append(a.expansion, chip)
+}
+
+// OnSample sets a function to call with the mixer's output after every CPU cycle
+func (a *APU) OnSample(handler func(sample float32)) {
+	a.onSample = handler
+}
+
+// PowerUp puts the APU in its power up state: every channel is silenced and the frame sequencer starts as if 0 were
+// written to $4015 and $4017
+func (a *APU) PowerUp() {
+	a.writeStatus(0)
+	a.frame.write(0, false)
+	a.updateIRQ()
+}
+
+// Reset handles the console's reset button. Every channel is silenced as if 0 were written to $4015, and the frame
+// sequencer restarts in the mode last written to $4017
+func (a *APU) Reset() {
+	a.writeStatus(0)
+	a.frame.write(a.frame.mode, false)
+	a.updateIRQ()
+}
+
+// Tick runs the APU for one CPU cycle
+func (a *APU) Tick() {
+	a.cycle++
+	// The pulse timers run at half the CPU clock
+	if a.cycle%2 == 0 {
+		a.pulse1.ClockTimer()
+		a.pulse2.ClockTimer()
+	}
+	a.triangle.clockTimer()
+	a.noise.clockTimer()
+	a.dmc.clockTimer()
+
+	quarter, half := a.frame.clock()
+	if quarter {
+		a.pulse1.ClockEnvelope()
+		a.pulse2.ClockEnvelope()
+		a.noise.envelope.clock()
+		a.triangle.clockLinear()
+	}
+	if half {
+		a.pulse1.ClockLength()
+		a.pulse2.ClockLength()
+		a.triangle.length.clock()
+		a.noise.length.clock()
+		a.pulse1.clockSweep()
+		a.pulse2.clockSweep()
+	}
+
+	if a.dmc.needsFetch() {
+		a.fetchSample()
+	}
+	a.updateIRQ()
+
+	if a.onSample != nil {
+		a.onSample(a.Sample())
+	}
+}
+
+// fetchSample reads the next sample byte for the DMC, halting the CPU while it does
+func (a *APU) fetchSample() {
+	if a.stall != nil {
+		a.stall(dmcDMACycles)
+	}
+	var data uint8
+	if a.bus != nil {
+		data = a.bus.Read(a.dmc.address)
+	}
+	a.dmc.fill(data)
+}
+
+// Sample returns the current output of the mixer. The APU alone produces 0 to 1. Expansion chips are added on top at
+// their relative volume
+func (a *APU) Sample() float32 {
+	sample := mix(a.pulse1.output(), a.pulse2.output(), a.triangle.output(), a.noise.output(), a.dmc.output())
+	for _, chip := range a.expansion {
+		sample += chip.Sample() * chip.Volume()
+	}
+	return sample
+}
+
+// updateIRQ drives the IRQ lines from the interrupt flags
+func (a *APU) updateIRQ() {
+	if a.frame.irq != a.frameIRQLine {
+		a.frameIRQLine = a.frame.irq
+		if a.frameIRQ != nil {
+			a.frameIRQ(a.frameIRQLine)
+		}
+	}
+	if a.dmc.irq != a.dmcIRQLine {
+		a.dmcIRQLine = a.dmc.irq
+		if a.dmcIRQ != nil {
+			a.dmcIRQ(a.dmcIRQLine)
+		}
+	}
+}
+
+// Read reads $4015. Every other register is write only and reads return 0
+func (a *APU) Read(address uint16) uint8 {
+	data, _ := a.ReadPartial(address)
+	return data
+}
+
+// ReadPartial reads $4015, which leaves bit 5 undriven. Nothing else in the APU's range drives the bus. Reading the
+// status clears the frame interrupt
+func (a *APU) ReadPartial(address uint16) (data, driven uint8) {
+	if address-bus.IOStart != statusRegister {
+		return 0, 0
+	}
+	if a.pulse1.Active() {
+		data |= statusPulse1
+	}
+	if a.pulse2.Active() {
+		data |= statusPulse2
+	}
+	if a.triangle.length.active() {
+		data |= statusTriangle
+	}
+	if a.noise.length.active() {
+		data |= statusNoise
+	}
+	if a.dmc.remaining > 0 {
+		data |= statusDMC
+	}
+	if a.frame.irq {
+		data |= statusFrameIRQ
+	}
+	if a.dmc.irq {
+		data |= statusDMCIRQ
+	}
+	a.frame.irq = false
+	a.updateIRQ()
+	return data, statusDriven
+}
+
+// Write writes one of the APU registers at $4000-$4013, $4015 or $4017
+func (a *APU) Write(address uint16, data uint8) {
+	register := address - bus.IOStart
+	switch {
+	case register < pulse2Registers:
+		a.pulse1.write(register-pulse1Registers, data)
+	case register < triangleRegisters:
+		a.pulse2.write(register-pulse2Registers, data)
+	case register < noiseRegisters:
+		a.triangle.write(register-triangleRegisters, data)
+	case register < dmcRegisters:
+		a.noise.write(register-noiseRegisters, data)
+	case register <= channelsEnd:
+		a.dmc.write(register-dmcRegisters, data)
+	case register == statusRegister:
+		a.writeStatus(data)
+	case register == frameRegister:
+		a.frame.write(data, a.cycle%2 == 1)
+	}
+	a.updateIRQ()
+}
+
+// writeStatus enables and disables the channels and acknowledges the DMC interrupt
+func (a *APU) writeStatus(data uint8) {
+	a.pulse1.SetEnabled(data&statusPulse1 != 0)
+	a.pulse2.SetEnabled(data&statusPulse2 != 0)
+	a.triangle.length.setEnabled(data&statusTriangle != 0)
+	a.noise.length.setEnabled(data&statusNoise != 0)
+	a.dmc.setEnabled(data&statusDMC != 0)
+}
@@ -0,0 +1,133 @@
+package apu
+
+// Periods of the delta modulation channel's output in CPU cycles. The PAL rates play samples at about the same pitch
+var (
+	ntscDMCRates = [16]uint16{428, 380, 340, 320, 286, 254, 226, 214, 190, 160, 142, 128, 106, 84, 72, 54}
+	palDMCRates  = [16]uint16{398, 354, 316, 298, 276, 236, 210, 198, 176, 148, 132, 118, 98, 78, 66, 50}
+)
+
+const (
+	dmcAddressBase uint16 = 0xC000
+	// Fetching a sample byte halts the CPU while the DMA takes the bus
+	dmcDMACycles = 4
+)
+
+// dmc is the delta modulation channel. It plays 1 bit delta encoded samples that it fetches from CPU memory itself,
+// stealing cycles from the CPU for each byte. http://wiki.nesdev.com/w/index.php/APU_DMC
+type dmc struct {
+	irqEnabled bool
+	irq        bool
+	loop       bool
+	timer      uint16
+	period     uint16
+	rates      *[16]uint16
+
+	// The 7 bit output level
+	level uint8
+
+	sampleAddress uint16
+	sampleLength  uint16
+	// The memory reader's position in the sample
+	address   uint16
+	remaining uint16
+	buffer    uint8
+	buffered  bool
+
+	// The output unit shifts out one byte at a time
+	shift   uint8
+	bits    uint8
+	silence bool
+}
+
+func newDMC() dmc {
+	return dmc{period: ntscDMCRates[0], rates: &ntscDMCRates, bits: 8, silence: true}
+}
+
+func (d *dmc) write(register uint16, data uint8) {
+	switch register {
+	case 0:
+		d.irqEnabled = data&0x80 != 0
+		if !d.irqEnabled {
+			d.irq = false
+		}
+		d.loop = data&0x40 != 0
+		d.period = d.rates[data&0x0F]
+	case 1:
+		d.level = data & 0x7F
+	case 2:
+		d.sampleAddress = dmcAddressBase | uint16(data)<<6
+	case 3:
+		d.sampleLength = uint16(data)<<4 | 1
+	}
+}
+
+// setEnabled starts or stops sample playback through $4015. Starting only restarts the sample if it has finished
+func (d *dmc) setEnabled(enabled bool) {
+	d.irq = false
+	if !enabled {
+		d.remaining = 0
+	} else if d.remaining == 0 {
+		d.restart()
+	}
+}
+
+func (d *dmc) restart() {
+	d.address = d.sampleAddress
+	d.remaining = d.sampleLength
+}
+
+// clockTimer runs the output unit, which is clocked every CPU cycle
+func (d *dmc) clockTimer() {
+	if d.timer > 0 {
+		d.timer--
+		return
+	}
+	d.timer = d.period - 1
+	if !d.silence {
+		if d.shift&0x01 != 0 {
+			if d.level <= 125 {
+				d.level += 2
+			}
+		} else if d.level >= 2 {
+			d.level -= 2
+		}
+	}
+	d.shift >>= 1
+	d.bits--
+	if d.bits == 0 {
+		d.bits = 8
+		d.silence = !d.buffered
+		d.shift = d.buffer
+		d.buffered = false
+	}
+}
+
+// needsFetch returns true when the sample buffer is empty and there is more of the sample to read
+func (d *dmc) needsFetch() bool {
+	return !d.buffered && d.remaining > 0
+}
+
+// fill puts a fetched byte in the sample buffer and moves the reader on, looping or raising an IRQ at the end of the
+// sample
+func (d *dmc) fill(data uint8) {
+	d.buffer = data
+	d.buffered = true
+	if d.address == 0xFFFF {
+		d.address = 0x8000
+	} else {
+		d.address++
+	}
+	d.remaining--
+	if d.remaining == 0 {
+		if d.loop {
+			d.restart()
+		} else if d.irqEnabled {
+			d.irq = true
+		}
+	}
+}
+
+// output returns the channel's current level, 0-127
+func (d *dmc) output() uint8 {
+	return d.level
+}
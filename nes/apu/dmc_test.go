@@ -0,0 +1,77 @@
+package apu
+
+import (
+	"reflect"
+	"testing"
+)
+
+// sampleMemory is a bus that serves sample bytes and records the addresses read
+type sampleMemory struct {
+	reads []uint16
+}
+
+func (m *sampleMemory) Read(address uint16) uint8 {
+	m.reads = append(m.reads, address)
+	return uint8(address)
+}
+
+func (m *sampleMemory) Write(address uint16, data uint8) {}
+
+func TestDMCDMA(t *testing.T) {
+	tests := []struct {
+		name      string
+		control   uint8
+		address   uint8
+		length    uint8
+		wantReads []uint16
+		wantIRQ   bool
+		wantLeft  uint16
+	}{
+		{"one byte", 0x0F, 0x00, 0x00, []uint16{0xC000}, false, 0},
+		{"one byte with IRQ", 0x8F, 0x00, 0x00, []uint16{0xC000}, true, 0},
+		{"looping", 0xCF, 0x00, 0x00, []uint16{0xC000}, false, 1},
+		{"17 bytes at $FFC0", 0x0F, 0xFF, 0x01, []uint16{0xFFC0}, false, 16},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			memory := &sampleMemory{}
+			var stalls []int
+			a := New()
+			a.ConnectBus(memory, func(cycles int) {
+				stalls = append(stalls, cycles)
+			})
+			a.PowerUp()
+			a.Write(0x4010, test.control)
+			a.Write(0x4012, test.address)
+			a.Write(0x4013, test.length)
+			a.Write(0x4015, statusDMC)
+			a.Tick()
+
+			if !reflect.DeepEqual(memory.reads, test.wantReads) {
+				t.Errorf("fetched %04X, want %04X", memory.reads, test.wantReads)
+			}
+			if !reflect.DeepEqual(stalls, []int{dmcDMACycles}) {
+				t.Errorf("stalled the CPU for %v cycles, want [%d]", stalls, dmcDMACycles)
+			}
+			if a.dmc.irq != test.wantIRQ {
+				t.Errorf("IRQ = %v, want %v", a.dmc.irq, test.wantIRQ)
+			}
+			if a.dmc.remaining != test.wantLeft {
+				t.Errorf("%d bytes left, want %d", a.dmc.remaining, test.wantLeft)
+			}
+			// The buffer is full so nothing more is fetched until the output unit empties it
+			a.Tick()
+			if len(memory.reads) != 1 {
+				t.Errorf("fetched %d bytes with a full buffer, want 1", len(memory.reads))
+			}
+		})
+	}
+
+	// The address wraps from $FFFF to $8000
+	d := newDMC()
+	d.address, d.remaining = 0xFFFF, 2
+	d.fill(0)
+	if d.address != 0x8000 {
+		t.Errorf("address after $FFFF = $%04X, want $8000", d.address)
+	}
+}
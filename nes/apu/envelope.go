@@ -0,0 +1,90 @@
+package apu
+
+// lengthTable converts the 5 bit length index written to a channel into the number of half frames it plays for
+var lengthTable = [32]uint8{
+	10, 254, 20, 2, 40, 4, 80, 6, 160, 8, 60, 10, 14, 12, 26, 14,
+	12, 16, 24, 18, 48, 20, 96, 22, 192, 24, 72, 26, 16, 28, 32, 30,
+}
+
+// lengthCounter silences a channel once it has played for a set number of half frames.
+// http://wiki.nesdev.com/w/index.php/APU_Length_Counter
+type lengthCounter struct {
+	enabled bool
+	halt    bool
+	value   uint8
+}
+
+// load loads the counter from the length table, if the channel is enabled
+func (l *lengthCounter) load(index uint8) {
+	if l.enabled {
+		l.value = lengthTable[index>>3]
+	}
+}
+
+// setEnabled enables or disables the channel through $4015. Disabling it silences it immediately
+func (l *lengthCounter) setEnabled(enabled bool) {
+	l.enabled = enabled
+	if !enabled {
+		l.value = 0
+	}
+}
+
+// clock counts down once per half frame unless halted
+func (l *lengthCounter) clock() {
+	if l.value > 0 && !l.halt {
+		l.value--
+	}
+}
+
+// active returns true while the counter has not run out
+func (l *lengthCounter) active() bool {
+	return l.value > 0
+}
+
+// envelope produces a decaying volume, or a constant volume, for the pulse and noise channels.
+// http://wiki.nesdev.com/w/index.php/APU_Envelope
+type envelope struct {
+	start    bool
+	loop     bool
+	constant bool
+	// The constant volume, which is also the period of the decay
+	period  uint8
+	divider uint8
+	decay   uint8
+}
+
+// write sets the envelope from the low 6 bits of the channel's first register
+func (e *envelope) write(data uint8) {
+	e.loop = data&0x20 != 0
+	e.constant = data&0x10 != 0
+	e.period = data & 0x0F
+}
+
+// clock runs the envelope once per quarter frame
+func (e *envelope) clock() {
+	if e.start {
+		e.start = false
+		e.decay = 15
+		e.divider = e.period
+		return
+	}
+	if e.divider > 0 {
+		e.divider--
+		return
+	}
+	e.divider = e.period
+	switch {
+	case e.decay > 0:
+		e.decay--
+	case e.loop:
+		e.decay = 15
+	}
+}
+
+// volume returns the current volume, 0-15
+func (e *envelope) volume() uint8 {
+	if e.constant {
+		return e.period
+	}
+	return e.decay
+}
@@ -0,0 +1,39 @@
+package apu
+
+import "testing"
+
+func TestLengthTable(t *testing.T) {
+	tests := []struct {
+		index uint8
+		want  uint8
+	}{
+		{0x00, 10}, {0x01, 254}, {0x02, 20}, {0x03, 2},
+		{0x08, 160}, {0x0F, 14}, {0x10, 12}, {0x11, 16},
+		{0x18, 192}, {0x1D, 28}, {0x1E, 32}, {0x1F, 30},
+	}
+	for _, test := range tests {
+		a := New()
+		a.PowerUp()
+		a.Write(0x4015, statusPulse1|statusTriangle|statusNoise)
+		a.Write(0x4003, test.index<<3)
+		a.Write(0x400B, test.index<<3)
+		a.Write(0x400F, test.index<<3)
+		for name, got := range map[string]uint8{
+			"pulse":    a.pulse1.length.value,
+			"triangle": a.triangle.length.value,
+			"noise":    a.noise.length.value,
+		} {
+			if got != test.want {
+				t.Errorf("%s length for index $%02X = %d, want %d", name, test.index, got, test.want)
+			}
+		}
+	}
+
+	// Disabled channels ignore the length load
+	a := New()
+	a.PowerUp()
+	a.Write(0x4003, 0x08)
+	if a.pulse1.length.value != 0 {
+		t.Errorf("disabled pulse loaded length %d, want 0", a.pulse1.length.value)
+	}
+}
@@ -0,0 +1,98 @@
+package apu
+
+// The frame sequencer clocks the envelopes, linear counter, length counters and sweep units at roughly 240Hz, or
+// 200Hz on PAL. Steps are in CPU cycles after the sequencer was reset.
+// http://wiki.nesdev.com/w/index.php/APU_Frame_Counter
+type frameSequence struct {
+	fourStep       [4]uint32
+	fiveStep       [5]uint32
+	fourStepPeriod uint32
+	fiveStepPeriod uint32
+}
+
+var (
+	ntscFrameSequence = frameSequence{
+		fourStep:       [4]uint32{7457, 14913, 22371, 29829},
+		fiveStep:       [5]uint32{7457, 14913, 22371, 29829, 37281},
+		fourStepPeriod: 29830,
+		fiveStepPeriod: 37282,
+	}
+	palFrameSequence = frameSequence{
+		fourStep:       [4]uint32{8313, 16627, 24939, 33253},
+		fiveStep:       [5]uint32{8313, 16627, 24939, 33253, 41565},
+		fourStepPeriod: 33254,
+		fiveStepPeriod: 41566,
+	}
+)
+
+// frameCounter is the frame sequencer
+type frameCounter struct {
+	sequence   *frameSequence
+	fiveStep   bool
+	irqInhibit bool
+	irq        bool
+	cycle      uint32
+	// The last value written to $4017, which the sequencer restarts with when the console is reset
+	mode uint8
+	// Writes to $4017 reset the sequencer 3 or 4 CPU cycles later
+	resetDelay int
+}
+
+// clock runs the sequencer for one CPU cycle and returns whether a quarter or half frame event happens
+func (f *frameCounter) clock() (quarter, half bool) {
+	if f.resetDelay > 0 {
+		f.resetDelay--
+		if f.resetDelay == 0 {
+			f.cycle = 0
+			// Switching to the 5 step sequence clocks everything immediately
+			if f.fiveStep {
+				return true, true
+			}
+			return false, false
+		}
+	}
+	f.cycle++
+	s := f.sequence
+	if f.fiveStep {
+		switch f.cycle {
+		case s.fiveStep[0], s.fiveStep[2]:
+			quarter = true
+		case s.fiveStep[1], s.fiveStep[4]:
+			quarter, half = true, true
+		}
+		if f.cycle >= s.fiveStepPeriod {
+			f.cycle = 0
+		}
+		return quarter, half
+	}
+
+	switch f.cycle {
+	case s.fourStep[0], s.fourStep[2]:
+		quarter = true
+	case s.fourStep[1], s.fourStep[3]:
+		quarter, half = true, true
+	}
+	// The IRQ flag is set on the last three cycles of the sequence: the one before the last step, the last step and
+	// the cycle the sequence wraps around on. Reading $4015 in between clears it only for it to be set again
+	if f.cycle >= s.fourStep[3]-1 && !f.irqInhibit {
+		f.irq = true
+	}
+	if f.cycle >= s.fourStepPeriod {
+		f.cycle = 0
+	}
+	return quarter, half
+}
+
+// write handles a write to $4017. oddCycle is whether the write happens on an odd CPU cycle
+func (f *frameCounter) write(data uint8, oddCycle bool) {
+	f.mode = data
+	f.fiveStep = data&0x80 != 0
+	f.irqInhibit = data&0x40 != 0
+	if f.irqInhibit {
+		f.irq = false
+	}
+	f.resetDelay = 3
+	if oddCycle {
+		f.resetDelay = 4
+	}
+}
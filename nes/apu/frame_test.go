@@ -0,0 +1,70 @@
+package apu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFrameIRQ(t *testing.T) {
+	tests := []struct {
+		name     string
+		sequence *frameSequence
+		mode     uint8
+		want     []int
+	}{
+		{"NTSC", &ntscFrameSequence, 0x00, []int{29828, 29829, 29830}},
+		{"PAL", &palFrameSequence, 0x00, []int{33252, 33253, 33254}},
+		{"inhibited", &ntscFrameSequence, 0x40, nil},
+		{"five step", &ntscFrameSequence, 0x80, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := frameCounter{sequence: test.sequence}
+			f.write(test.mode, false)
+			for f.resetDelay > 0 {
+				f.clock()
+			}
+			// Acknowledge the interrupt after every cycle, like a program polling $4015, to see each cycle it is set on
+			var got []int
+			for cycle := 1; cycle <= int(test.sequence.fiveStepPeriod); cycle++ {
+				f.clock()
+				if f.irq {
+					got = append(got, cycle)
+				}
+				f.irq = false
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("IRQ set on cycles %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResetKeepsFrameMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     uint8
+		fiveStep bool
+		inhibit  bool
+	}{
+		{"four step", 0x00, false, false},
+		{"five step", 0x80, true, false},
+		{"inhibited", 0x40, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := New()
+			a.PowerUp()
+			a.Write(0x4017, test.mode)
+			a.Reset()
+			if a.frame.fiveStep != test.fiveStep || a.frame.irqInhibit != test.inhibit {
+				t.Errorf("after reset five step = %v and inhibit = %v, want %v and %v",
+					a.frame.fiveStep, a.frame.irqInhibit, test.fiveStep, test.inhibit)
+			}
+			a.PowerUp()
+			if a.frame.fiveStep || a.frame.irqInhibit {
+				t.Errorf("after power up five step = %v and inhibit = %v, want both false", a.frame.fiveStep, a.frame.irqInhibit)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package apu
+
+// The 2A03 mixes its channels through two resistor DACs whose output is not linear in the channel levels. Lookup
+// tables indexed by the summed levels approximate them closely. http://wiki.nesdev.com/w/index.php/APU_Mixer
+var (
+	// Indexed by pulse1 + pulse2, 0-30
+	pulseTable [31]float32
+	// Indexed by 3*triangle + 2*noise + dmc, 0-202
+	tndTable [203]float32
+)
+
+func init() {
+	for i := 1; i < len(pulseTable); i++ {
+		pulseTable[i] = 95.52 / (8128.0/float32(i) + 100)
+	}
+	for i := 1; i < len(tndTable); i++ {
+		tndTable[i] = 163.67 / (24329.0/float32(i) + 100)
+	}
+}
+
+// mix combines the channel levels into a single sample between 0 and 1
+func mix(pulse1, pulse2, triangle, noise, dmc uint8) float32 {
+	return pulseTable[pulse1+pulse2] + tndTable[3*int(triangle)+2*int(noise)+int(dmc)]
+}
@@ -0,0 +1,62 @@
+package apu
+
+// Timer periods of the noise channel in CPU cycles. PAL consoles use shorter periods to make up for their slower CPU
+var (
+	ntscNoisePeriods = [16]uint16{4, 8, 16, 32, 64, 96, 128, 160, 202, 254, 380, 508, 762, 1016, 2034, 4068}
+	palNoisePeriods  = [16]uint16{4, 8, 14, 30, 60, 88, 118, 148, 188, 236, 354, 472, 708, 944, 1890, 3778}
+)
+
+// noise produces pseudo-random output from a 15 bit linear feedback shift register.
+// http://wiki.nesdev.com/w/index.php/APU_Noise
+type noise struct {
+	envelope envelope
+	length   lengthCounter
+
+	// In short mode the feedback comes from bit 6 instead of bit 1, giving a sequence of only 93 or 31 steps
+	short   bool
+	shift   uint16
+	timer   uint16
+	period  uint16
+	periods *[16]uint16
+}
+
+func newNoise() noise {
+	return noise{shift: 1, period: ntscNoisePeriods[0], periods: &ntscNoisePeriods}
+}
+
+func (n *noise) write(register uint16, data uint8) {
+	switch register {
+	case 0:
+		n.length.halt = data&0x20 != 0
+		n.envelope.write(data)
+	case 2:
+		n.short = data&0x80 != 0
+		n.period = n.periods[data&0x0F]
+	case 3:
+		n.length.load(data)
+		n.envelope.start = true
+	}
+}
+
+// clockTimer runs the timer, which is clocked every CPU cycle
+func (n *noise) clockTimer() {
+	if n.timer > 0 {
+		n.timer--
+		return
+	}
+	n.timer = n.period - 1
+	tap := uint(1)
+	if n.short {
+		tap = 6
+	}
+	feedback := (n.shift ^ n.shift>>tap) & 0x01
+	n.shift = n.shift>>1 | feedback<<14
+}
+
+// output returns the channel's current level, 0-15
+func (n *noise) output() uint8 {
+	if !n.length.active() || n.shift&0x01 != 0 {
+		return 0
+	}
+	return n.envelope.volume()
+}
@@ -0,0 +1,149 @@
+package apu
+
+// dutyTable holds the 8 step waveforms of the four pulse duty cycles: 12.5%, 25%, 50% and 25% negated
+var dutyTable = [4][8]uint8{
+	{0, 1, 0, 0, 0, 0, 0, 0},
+	{0, 1, 1, 0, 0, 0, 0, 0},
+	{0, 1, 1, 1, 1, 0, 0, 0},
+	{1, 0, 0, 1, 1, 1, 1, 1},
+}
+
+// Pulse is a square wave channel with an envelope and length counter but no sweep unit. The APU's pulse channels add a
+// sweep unit to it, and expansion chips with APU style pulses, like the MMC5, use it as it is.
+// http://wiki.nesdev.com/w/index.php/APU_Pulse
+type Pulse struct {
+	envelope envelope
+	length   lengthCounter
+
+	duty  uint8
+	step  uint8
+	timer uint16
+	// The 11 bit timer period
+	period uint16
+}
+
+// Write writes one of the channel's duty/volume, period low or period high/length registers. Register 1, the sweep
+// register on the APU, is ignored
+func (p *Pulse) Write(register uint16, data uint8) {
+	switch register {
+	case 0:
+		p.duty = data >> 6
+		p.length.halt = data&0x20 != 0
+		p.envelope.write(data)
+	case 2:
+		p.period = p.period&0x0700 | uint16(data)
+	case 3:
+		p.period = p.period&0x00FF | uint16(data&0x07)<<8
+		p.length.load(data)
+		p.step = 0
+		p.envelope.start = true
+	}
+}
+
+// SetEnabled enables or disables the channel. Disabling it silences it immediately
+func (p *Pulse) SetEnabled(enabled bool) {
+	p.length.setEnabled(enabled)
+}
+
+// ClockTimer runs the timer, which is clocked every other CPU cycle
+func (p *Pulse) ClockTimer() {
+	if p.timer > 0 {
+		p.timer--
+		return
+	}
+	p.timer = p.period
+	p.step = (p.step + 1) & 0x07
+}
+
+// ClockEnvelope runs the envelope, once per quarter frame on the APU
+func (p *Pulse) ClockEnvelope() {
+	p.envelope.clock()
+}
+
+// ClockLength runs the length counter, once per half frame on the APU
+func (p *Pulse) ClockLength() {
+	p.length.clock()
+}
+
+// Active returns true while the length counter has not run out
+func (p *Pulse) Active() bool {
+	return p.length.active()
+}
+
+// Output returns the channel's current level, 0-15
+func (p *Pulse) Output() uint8 {
+	if !p.length.active() || dutyTable[p.duty][p.step] == 0 {
+		return 0
+	}
+	return p.envelope.volume()
+}
+
+// pulse is one of the APU's two square wave channels, a Pulse with a sweep unit
+type pulse struct {
+	Pulse
+
+	// The first pulse channel negates its sweep with one's complement and the second with two's complement
+	onesComplement bool
+
+	sweepEnabled bool
+	sweepPeriod  uint8
+	sweepNegate  bool
+	sweepShift   uint8
+	sweepDivider uint8
+	sweepReload  bool
+}
+
+// write writes one of the channel's four registers
+func (p *pulse) write(register uint16, data uint8) {
+	if register != 1 {
+		p.Pulse.Write(register, data)
+		return
+	}
+	p.sweepEnabled = data&0x80 != 0
+	p.sweepPeriod = (data >> 4) & 0x07
+	p.sweepNegate = data&0x08 != 0
+	p.sweepShift = data & 0x07
+	p.sweepReload = true
+}
+
+// sweepTarget returns the period the sweep unit is moving towards. It is calculated continuously and mutes the
+// channel when it overflows, even when the sweep is disabled
+func (p *pulse) sweepTarget() uint16 {
+	delta := p.period >> p.sweepShift
+	if !p.sweepNegate {
+		return p.period + delta
+	}
+	if p.onesComplement {
+		delta++
+	}
+	if delta > p.period {
+		return 0
+	}
+	return p.period - delta
+}
+
+// muted returns true if the sweep unit is silencing the channel
+func (p *pulse) muted() bool {
+	return p.period < 8 || p.sweepTarget() > 0x07FF
+}
+
+// clockSweep runs the sweep unit once per half frame
+func (p *pulse) clockSweep() {
+	if p.sweepDivider == 0 && p.sweepEnabled && p.sweepShift > 0 && !p.muted() {
+		p.period = p.sweepTarget()
+	}
+	if p.sweepDivider == 0 || p.sweepReload {
+		p.sweepDivider = p.sweepPeriod
+		p.sweepReload = false
+	} else {
+		p.sweepDivider--
+	}
+}
+
+// output returns the channel's current level, 0-15
+func (p *pulse) output() uint8 {
+	if p.muted() {
+		return 0
+	}
+	return p.Output()
+}
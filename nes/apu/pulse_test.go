@@ -0,0 +1,37 @@
+package apu
+
+import "testing"
+
+func TestSweepTarget(t *testing.T) {
+	tests := []struct {
+		name           string
+		onesComplement bool
+		period         uint16
+		sweep          uint8
+		want           uint16
+		muted          bool
+	}{
+		{"pulse 1 up", true, 0x100, 0x81, 0x180, false},
+		{"pulse 2 up", false, 0x100, 0x81, 0x180, false},
+		{"pulse 1 down", true, 0x100, 0x89, 0x07F, false},
+		{"pulse 2 down", false, 0x100, 0x89, 0x080, false},
+		{"pulse 1 down by shift 0", true, 0x100, 0x88, 0x000, false},
+		{"pulse 2 down by shift 0", false, 0x100, 0x88, 0x000, false},
+		{"overflow mutes", false, 0x600, 0x81, 0x900, true},
+		{"overflow mutes while disabled", false, 0x600, 0x01, 0x900, true},
+		{"short period mutes", false, 0x007, 0x89, 0x004, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := pulse{onesComplement: test.onesComplement}
+			p.period = test.period
+			p.write(1, test.sweep)
+			if got := p.sweepTarget(); got != test.want {
+				t.Errorf("sweep target = $%03X, want $%03X", got, test.want)
+			}
+			if got := p.muted(); got != test.muted {
+				t.Errorf("muted = %v, want %v", got, test.muted)
+			}
+		})
+	}
+}
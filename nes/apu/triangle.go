@@ -0,0 +1,68 @@
+package apu
+
+// triangleSequence is the 32 step waveform of the triangle channel
+var triangleSequence = [32]uint8{
+	15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0,
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// triangle is the triangle wave channel. Besides the length counter it has a linear counter with a finer resolution.
+// http://wiki.nesdev.com/w/index.php/APU_Triangle
+type triangle struct {
+	length lengthCounter
+
+	// The control flag halts the length counter and keeps the linear counter reloading
+	control       bool
+	linearPeriod  uint8
+	linearCounter uint8
+	linearReload  bool
+
+	step   uint8
+	timer  uint16
+	period uint16
+}
+
+func (t *triangle) write(register uint16, data uint8) {
+	switch register {
+	case 0:
+		t.control = data&0x80 != 0
+		t.length.halt = t.control
+		t.linearPeriod = data & 0x7F
+	case 2:
+		t.period = t.period&0x0700 | uint16(data)
+	case 3:
+		t.period = t.period&0x00FF | uint16(data&0x07)<<8
+		t.length.load(data)
+		t.linearReload = true
+	}
+}
+
+// clockTimer runs the timer, which is clocked every CPU cycle. The sequence only moves while both counters are
+// non-zero, so silencing the channel leaves it at its current level rather than dropping to 0
+func (t *triangle) clockTimer() {
+	if t.timer > 0 {
+		t.timer--
+		return
+	}
+	t.timer = t.period
+	if t.length.active() && t.linearCounter > 0 {
+		t.step = (t.step + 1) & 0x1F
+	}
+}
+
+// clockLinear runs the linear counter once per quarter frame
+func (t *triangle) clockLinear() {
+	if t.linearReload {
+		t.linearCounter = t.linearPeriod
+	} else if t.linearCounter > 0 {
+		t.linearCounter--
+	}
+	if !t.control {
+		t.linearReload = false
+	}
+}
+
+// output returns the channel's current level, 0-15
+func (t *triangle) output() uint8 {
+	return triangleSequence[t.step]
+}
@@ -0,0 +1,13 @@
+// Package audio turns the APU's output into audio at a standard sample rate and writes it out
+package audio
+
+// Common output sample rates in Hz
+const (
+	Rate44100 = 44100
+	Rate48000 = 48000
+)
+
+// Sink consumes a stream of samples. Samples are nominally between -1 and 1
+type Sink interface {
+	WriteSample(sample float32)
+}
@@ -0,0 +1,181 @@
+package audio_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/kevinwmiller/nesgo/nes/audio"
+)
+
+// seekBuffer is an in-memory io.WriteSeeker
+type seekBuffer struct {
+	data   []byte
+	offset int
+}
+
+func (b *seekBuffer) Write(p []byte) (int, error) {
+	if end := b.offset + len(p); end > len(b.data) {
+		b.data = append(b.data, make([]byte, end-len(b.data))...)
+	}
+	copy(b.data[b.offset:], p)
+	b.offset += len(p)
+	return len(p), nil
+}
+
+func (b *seekBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += int64(b.offset)
+	case io.SeekEnd:
+		offset += int64(len(b.data))
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	b.offset = int(offset)
+	return offset, nil
+}
+
+// sampleRecorder is a Sink that keeps every sample written to it
+type sampleRecorder []float32
+
+func (r *sampleRecorder) WriteSample(sample float32) {
+	*r = append(*r, sample)
+}
+
+// wavSizes returns the RIFF chunk size and data chunk size from a WAV header
+func wavSizes(t *testing.T, data []byte) (uint32, uint32) {
+	if len(data) < 44 {
+		t.Fatalf("file is %d bytes, shorter than the header", len(data))
+	}
+	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" || string(data[36:40]) != "data" {
+		t.Fatalf("malformed header % X", data[:44])
+	}
+	return binary.LittleEndian.Uint32(data[4:]), binary.LittleEndian.Uint32(data[40:])
+}
+
+func TestWAVWriterSizes(t *testing.T) {
+	tests := []struct {
+		name     string
+		samples  int
+		riffSize uint32
+		dataSize uint32
+	}{
+		{"empty", 0, 36, 0},
+		{"one sample", 1, 38, 2},
+		{"one second", audio.Rate44100, 36 + 2*audio.Rate44100, 2 * audio.Rate44100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := &seekBuffer{}
+			wav, err := audio.NewWAVWriter(buf, audio.Rate44100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < test.samples; i++ {
+				wav.WriteSample(0.25)
+			}
+			if err := wav.Close(); err != nil {
+				t.Fatal(err)
+			}
+			riffSize, dataSize := wavSizes(t, buf.data)
+			if riffSize != test.riffSize || dataSize != test.dataSize {
+				t.Errorf("RIFF size %d and data size %d, want %d and %d", riffSize, dataSize, test.riffSize, test.dataSize)
+			}
+			if len(buf.data) != 44+int(test.dataSize) {
+				t.Errorf("file is %d bytes, want %d", len(buf.data), 44+test.dataSize)
+			}
+			if buf.offset != len(buf.data) {
+				t.Errorf("left at offset %d, want the end of the file at %d", buf.offset, len(buf.data))
+			}
+		})
+	}
+}
+
+func TestWAVWriterWithoutSeeking(t *testing.T) {
+	var buf bytes.Buffer
+	wav, err := audio.NewWAVWriter(&buf, audio.Rate48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		wav.WriteSample(0)
+	}
+	if err := wav.Close(); err != nil {
+		t.Fatal(err)
+	}
+	riffSize, dataSize := wavSizes(t, buf.Bytes())
+	if riffSize != math.MaxUint32 || dataSize != math.MaxUint32 {
+		t.Errorf("RIFF size %d and data size %d, want both left at %d", riffSize, dataSize, uint32(math.MaxUint32))
+	}
+	if buf.Len() != 44+200 {
+		t.Errorf("file is %d bytes, want %d", buf.Len(), 44+200)
+	}
+}
+
+func TestWAVWriterClipping(t *testing.T) {
+	tests := []struct {
+		sample float32
+		want   int16
+	}{
+		{0, 0},
+		{0.5, math.MaxInt16 / 2},
+		{1, math.MaxInt16},
+		{1.5, math.MaxInt16},
+		{-1, -math.MaxInt16},
+		{-3, -math.MaxInt16},
+	}
+	buf := &seekBuffer{}
+	wav, err := audio.NewWAVWriter(buf, audio.Rate44100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		wav.WriteSample(test.sample)
+	}
+	if err := wav.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i, test := range tests {
+		got := int16(binary.LittleEndian.Uint16(buf.data[44+2*i:]))
+		if got != test.want {
+			t.Errorf("sample %v written as %d, want %d", test.sample, got, test.want)
+		}
+	}
+}
+
+func TestResamplerDCStep(t *testing.T) {
+	const (
+		inputRate = 1789773
+		level     = 0.5
+	)
+	var out sampleRecorder
+	r := audio.NewResampler(inputRate, audio.Rate44100, &out)
+	for i := 0; i < inputRate/100; i++ {
+		r.WriteSample(0)
+	}
+	silent := len(out)
+	for i := 0; i < inputRate/10; i++ {
+		r.WriteSample(level)
+	}
+
+	for i, sample := range out[:silent] {
+		if sample != 0 {
+			t.Fatalf("output sample %d = %v before the step, want 0", i, sample)
+		}
+	}
+	// The impulse is 16 output samples wide, so the output has settled well before the last few samples
+	for i, sample := range out[len(out)-100:] {
+		if math.Abs(float64(sample)-level) > 1e-6 {
+			t.Fatalf("output sample %d = %v after the step, want %v", len(out)-100+i, sample, level)
+		}
+	}
+	if want := (inputRate/100 + inputRate/10) * audio.Rate44100 / inputRate; len(out) < want-1 || len(out) > want+1 {
+		t.Errorf("%d output samples, want %d", len(out), want)
+	}
+}
@@ -0,0 +1,33 @@
+package audio
+
+// Fade passes samples through unchanged for a while, then fades them out linearly and drops everything after the fade.
+// It is used to end recordings of music that loops forever
+type Fade struct {
+	sink Sink
+	// The sample the fade starts on, how many samples it lasts and how many samples have been written
+	start    uint64
+	length   uint64
+	position uint64
+}
+
+// NewFade returns a Fade that starts fading after start samples and is silent length samples later
+func NewFade(start, length uint64, sink Sink) *Fade {
+	return &Fade{sink: sink, start: start, length: length}
+}
+
+// WriteSample scales a sample by the fade and writes it to the next sink
+func (f *Fade) WriteSample(sample float32) {
+	if f.Done() {
+		return
+	}
+	if f.position >= f.start {
+		sample *= 1 - float32(f.position-f.start)/float32(f.length)
+	}
+	f.position++
+	f.sink.WriteSample(sample)
+}
+
+// Done returns true once the fade is over
+func (f *Fade) Done() bool {
+	return f.position >= f.start+f.length
+}
@@ -0,0 +1,70 @@
+package audio
+
+import "math"
+
+// Corner frequencies of the filters between the 2A03 and the console's audio output.
+// http://wiki.nesdev.com/w/index.php/APU_Mixer
+const (
+	highPass1Cutoff = 90
+	highPass2Cutoff = 440
+	lowPassCutoff   = 14000
+)
+
+// filter is a first order high-pass or low-pass filter
+type filter struct {
+	highPass bool
+	// The smoothing factor, derived from the RC time constant and the sample period
+	alpha  float64
+	input  float64
+	output float64
+}
+
+func newFilter(cutoff, rate float64, highPass bool) filter {
+	rc := 1 / (2 * math.Pi * cutoff)
+	dt := 1 / rate
+	f := filter{highPass: highPass}
+	if highPass {
+		f.alpha = rc / (rc + dt)
+	} else {
+		f.alpha = dt / (rc + dt)
+	}
+	return f
+}
+
+func (f *filter) apply(sample float64) float64 {
+	if f.highPass {
+		f.output = f.alpha * (f.output + sample - f.input)
+	} else {
+		f.output += f.alpha * (sample - f.output)
+	}
+	f.input = sample
+	return f.output
+}
+
+// Filters passes samples through the console's output filters, two high-pass filters that remove the DC offset and
+// a low-pass filter, before handing them to the next sink
+type Filters struct {
+	filters [3]filter
+	sink    Sink
+}
+
+// NewFilters returns the filter chain for samples at rate Hz
+func NewFilters(rate float64, sink Sink) *Filters {
+	return &Filters{
+		filters: [3]filter{
+			newFilter(highPass1Cutoff, rate, true),
+			newFilter(highPass2Cutoff, rate, true),
+			newFilter(lowPassCutoff, rate, false),
+		},
+		sink: sink,
+	}
+}
+
+// WriteSample filters a sample and writes it to the next sink
+func (f *Filters) WriteSample(sample float32) {
+	s := float64(sample)
+	for i := range f.filters {
+		s = f.filters[i].apply(s)
+	}
+	f.sink.WriteSample(float32(s))
+}
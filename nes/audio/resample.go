@@ -0,0 +1,88 @@
+package audio
+
+import "math"
+
+// The resampler builds its output from band-limited steps. Every change in the input adds a windowed sinc impulse to
+// the output, which is integrated as it is read out. Input that holds its value costs almost nothing, which suits the
+// APU since its output only changes occasionally even though it runs at nearly 1.8MHz
+const (
+	// The width of the impulse in output samples
+	kernelWidth = 16
+	// The number of sub-sample positions the impulse is calculated for
+	kernelPhases = 64
+	// The cutoff as a fraction of the output rate. Slightly below the Nyquist frequency so the window's transition
+	// band does not alias
+	kernelCutoff = 0.45
+)
+
+// kernels holds the impulse for each sub-sample phase. Each is normalised to sum to 1 so a step of size d adds up to
+// exactly d once it has been integrated
+var kernels [kernelPhases][kernelWidth]float64
+
+func init() {
+	for phase := range kernels {
+		offset := float64(phase) / kernelPhases
+		var sum float64
+		for tap := range kernels[phase] {
+			t := float64(tap) - kernelWidth/2 - offset + 1
+			v := 2 * kernelCutoff * sinc(2*kernelCutoff*t)
+			// Blackman window over the width of the kernel
+			w := (t + kernelWidth/2) / kernelWidth
+			v *= 0.42 - 0.5*math.Cos(2*math.Pi*w) + 0.08*math.Cos(4*math.Pi*w)
+			kernels[phase][tap] = v
+			sum += v
+		}
+		for tap := range kernels[phase] {
+			kernels[phase][tap] /= sum
+		}
+	}
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// Resampler converts samples at one rate to a lower rate without aliasing
+type Resampler struct {
+	// Output samples per input sample
+	ratio float64
+	// The time of the next input sample since the last output sample, in output samples
+	position float64
+	last     float32
+	// The pending impulses, a ring indexed from start. deltas[start] is the next output sample
+	deltas     [kernelWidth]float64
+	start      int
+	integrator float64
+	sink       Sink
+}
+
+// NewResampler returns a Resampler from inputRate to outputRate Hz writing to sink
+func NewResampler(inputRate, outputRate float64, sink Sink) *Resampler {
+	return &Resampler{
+		ratio: outputRate / inputRate,
+		sink:  sink,
+	}
+}
+
+// WriteSample adds one input sample and writes any output samples that are complete
+func (r *Resampler) WriteSample(sample float32) {
+	if sample != r.last {
+		delta := float64(sample - r.last)
+		r.last = sample
+		kernel := &kernels[int(r.position*kernelPhases)%kernelPhases]
+		for tap, v := range kernel {
+			r.deltas[(r.start+tap)%kernelWidth] += delta * v
+		}
+	}
+	r.position += r.ratio
+	for r.position >= 1 {
+		r.position--
+		r.integrator += r.deltas[r.start]
+		r.deltas[r.start] = 0
+		r.start = (r.start + 1) % kernelWidth
+		r.sink.WriteSample(float32(r.integrator))
+	}
+}
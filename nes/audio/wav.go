@@ -0,0 +1,107 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// WAV files are written as mono 16 bit PCM. http://soundfile.sapp.org/doc/WaveFormat/
+const (
+	wavHeaderSize    = 44
+	wavFormatPCM     = 1
+	wavChannels      = 1
+	wavBitsPerSample = 16
+	wavBytesPerFrame = wavChannels * wavBitsPerSample / 8
+	// Offsets of the sizes that are only known once every sample has been written
+	wavRIFFSizeOffset = 4
+	wavDataSizeOffset = 40
+)
+
+// WAVWriter is a Sink that writes a WAV file. The sizes in the header are filled in by Close if the underlying writer
+// can seek, otherwise they are left at their maximum which most players treat as "read until the end"
+type WAVWriter struct {
+	w       io.Writer
+	buf     *bufio.Writer
+	samples uint32
+	err     error
+}
+
+// NewWAVWriter writes a WAV header for audio at rate Hz to w and returns a WAVWriter for the samples
+func NewWAVWriter(w io.Writer, rate int) (*WAVWriter, error) {
+	wav := &WAVWriter{w: w, buf: bufio.NewWriter(w)}
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'},
+		uint32(math.MaxUint32),
+		[4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(16),
+		uint16(wavFormatPCM),
+		uint16(wavChannels),
+		uint32(rate),
+		uint32(rate * wavBytesPerFrame),
+		uint16(wavBytesPerFrame),
+		uint16(wavBitsPerSample),
+		[4]byte{'d', 'a', 't', 'a'},
+		uint32(math.MaxUint32),
+	}
+	for _, field := range header {
+		if err := binary.Write(wav.buf, binary.LittleEndian, field); err != nil {
+			return nil, err
+		}
+	}
+	return wav, nil
+}
+
+// WriteSample writes one sample, clipping it to -1 to 1. Errors are kept and returned by Close
+func (w *WAVWriter) WriteSample(sample float32) {
+	if w.err != nil {
+		return
+	}
+	if sample > 1 {
+		sample = 1
+	} else if sample < -1 {
+		sample = -1
+	}
+	v := int16(sample * math.MaxInt16)
+	if err := w.buf.WriteByte(uint8(v)); err != nil {
+		w.err = err
+		return
+	}
+	if err := w.buf.WriteByte(uint8(uint16(v) >> 8)); err != nil {
+		w.err = err
+		return
+	}
+	w.samples++
+}
+
+// Close flushes the samples and fills in the header sizes. It does not close the underlying writer
+func (w *WAVWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	seeker, ok := w.w.(io.WriteSeeker)
+	if !ok {
+		return nil
+	}
+	dataSize := w.samples * wavBytesPerFrame
+	if err := writeSize(seeker, wavRIFFSizeOffset, wavHeaderSize-8+dataSize); err != nil {
+		return err
+	}
+	if err := writeSize(seeker, wavDataSizeOffset, dataSize); err != nil {
+		return err
+	}
+	_, err := seeker.Seek(0, io.SeekEnd)
+	return err
+}
+
+func writeSize(w io.WriteSeeker, offset int64, size uint32) error {
+	if _, err := w.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, size)
+}
@@ -1,19 +1,181 @@
 package bus
 
-// Bus connects the CPU to difference components that support reading and writing
+// CPU memory map. http://wiki.nesdev.com/w/index.php/CPU_memory_map
+const (
+	// 2KB of internal RAM, mirrored three times
+	RAMStart uint16 = 0x0000
+	RAMEnd   uint16 = 0x1FFF
+	RAMSize  uint16 = 0x0800
+
+	// The eight PPU registers, mirrored every 8 bytes
+	PPUStart     uint16 = 0x2000
+	PPUEnd       uint16 = 0x3FFF
+	PPURegisters uint16 = 0x0008
+
+	// APU and I/O registers
+	IOStart uint16 = 0x4000
+	IOEnd   uint16 = 0x401F
+	// Sprite DMA
+	OAMDMA uint16 = 0x4014
+
+	// Cartridge space. PRG ROM, PRG RAM and mapper registers
+	CartridgeStart uint16 = 0x4020
+	CartridgeEnd   uint16 = 0xFFFF
+)
+
+// Device is anything that can be read from and written to over a bus
+type Device interface {
+	Read(address uint16) uint8
+	Write(address uint16, data uint8)
+}
+
+// PartialDevice is implemented by devices that only drive some of the data lines when they are read, such as the
+// controller ports which only drive the low bits. ReadPartial returns the data along with a mask of the bits that
+// were driven. The remaining bits keep whatever value was last left on the bus
+type PartialDevice interface {
+	Device
+	ReadPartial(address uint16) (data, driven uint8)
+}
+
+// Peeker is implemented by devices that can be read without side effects, for tracers and debuggers. Peek returns
+// what a read of address would, along with a mask of the data lines that would be driven, without acknowledging
+// interrupts, advancing address registers or leaving the value on the bus
+type Peeker interface {
+	Peek(address uint16) (data, driven uint8)
+}
+
+// Observer watches writes to a range of the bus without responding to them. Some cartridges snoop writes meant for
+// other devices, like the MMC5 which follows writes to the PPU registers
+type Observer interface {
+	Observe(address uint16, data uint8)
+}
+
+// mapping assigns the address range start-end to a device. If mirror is non-zero the device only decodes the first
+// mirror bytes of the range and the rest of the range repeats them
+type mapping struct {
+	start   uint16
+	end     uint16
+	mirror  uint16
+	device  Device
+	partial PartialDevice
+}
+
+// Bus connects the CPU to difference components that support reading and writing.
+// Each access is routed to the device mapped at that address. The device receives the address of the first mirror.
+// Reads from addresses nothing is mapped to return the last value driven on the data bus, just like the hardware
 type Bus struct {
-	RAM [0xFFFF]uint8
+	mappings  []mapping
+	observers []mapping
+	openBus   uint8
+}
+
+// observerDevice lets an Observer sit in a mapping. Observers are never read from
+type observerDevice struct {
+	Observer
+}
+
+func (o observerDevice) Read(address uint16) uint8 {
+	return 0
+}
+
+func (o observerDevice) Write(address uint16, data uint8) {
+	o.Observe(address, data)
+}
+
+// New returns a bus with the 2KB of internal RAM mapped and mirrored through $1FFF
+func New() *Bus {
+	b := &Bus{}
+	b.Map(RAMStart, RAMEnd, RAMSize, NewRAM(RAMSize))
+	return b
+}
+
+// Map maps device onto the address range start-end, inclusive. mirror is the number of bytes the device decodes before
+// the range repeats, or zero if the device decodes the whole range. Devices mapped later take priority over earlier
+// mappings that overlap them
+func (b *Bus) Map(start, end, mirror uint16, device Device) {
+	partial, _ := device.(PartialDevice)
+	b.mappings = append([]mapping{{
+		start:   start,
+		end:     end,
+		mirror:  mirror,
+		device:  device,
+		partial: partial,
+	}}, b.mappings...)
+}
+
+// Observe has observer watch writes to the address range start-end, inclusive. mirror works the same as for Map.
+// Observers see the write after the device mapped at the address has handled it
+func (b *Bus) Observe(start, end, mirror uint16, observer Observer) {
+	b.observers = append(b.observers, mapping{
+		start:  start,
+		end:    end,
+		mirror: mirror,
+		device: observerDevice{observer},
+	})
+}
+
+// decode returns the mapping for address and the address to pass to its device
+func (b *Bus) decode(address uint16) (*mapping, uint16) {
+	for i := range b.mappings {
+		m := &b.mappings[i]
+		if address < m.start || address > m.end {
+			continue
+		}
+		if m.mirror != 0 {
+			address = m.start + (address-m.start)%m.mirror
+		}
+		return m, address
+	}
+	return nil, address
 }
 
 func (b *Bus) Write(address uint16, data uint8) {
-	if address >= 0x0000 && address < 0xFFFF {
-		b.RAM[address] = data
+	b.openBus = data
+	if m, address := b.decode(address); m != nil {
+		m.device.Write(address, data)
+	}
+	for i := range b.observers {
+		m := &b.observers[i]
+		if address < m.start || address > m.end {
+			continue
+		}
+		if m.mirror != 0 {
+			m.device.Write(m.start+(address-m.start)%m.mirror, data)
+		} else {
+			m.device.Write(address, data)
+		}
 	}
 }
 
 func (b *Bus) Read(address uint16) uint8 {
-	if address >= 0x0000 && address < 0xFFFF {
-		return b.RAM[address]
+	m, address := b.decode(address)
+	switch {
+	case m == nil:
+	case m.partial != nil:
+		data, driven := m.partial.ReadPartial(address)
+		b.openBus = data&driven | b.openBus&^driven
+	default:
+		b.openBus = m.device.Read(address)
 	}
-	return 0
+	return b.openBus
+}
+
+// Peek returns the value a read of address would return without any side effects. Devices that don't implement
+// Peeker can't be read safely, so their addresses return the open bus value. All of the data lines are driven
+func (b *Bus) Peek(address uint16) (uint8, uint8) {
+	m, address := b.decode(address)
+	if m == nil {
+		return b.openBus, 0xFF
+	}
+	p, ok := m.device.(Peeker)
+	if !ok {
+		return b.openBus, 0xFF
+	}
+	data, driven := p.Peek(address)
+	return data&driven | b.openBus&^driven, 0xFF
+}
+
+// OpenBus returns the last value driven on the data bus
+func (b *Bus) OpenBus() uint8 {
+	return b.openBus
 }
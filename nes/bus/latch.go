@@ -0,0 +1,38 @@
+package bus
+
+// DecayingLatch models a data bus whose value is held only by the capacitance of the lines, such as the PPU's internal
+// I/O bus. Each bit holds its value for a while after it was last driven and then decays to 0
+type DecayingLatch struct {
+	value uint8
+	// The time each bit was last driven to 1
+	refreshed [8]uint64
+	// How long a bit holds a 1 once it stops being driven
+	decay uint64
+}
+
+// NewDecayingLatch returns a latch whose bits decay after decay units of time. The unit is whatever the owner passes
+// as now, for example PPU dots
+func NewDecayingLatch(decay uint64) *DecayingLatch {
+	return &DecayingLatch{decay: decay}
+}
+
+// Drive sets the bits in mask to the matching bits of data at time now. Bits outside the mask are left alone
+func (l *DecayingLatch) Drive(data, mask uint8, now uint64) {
+	l.Value(now)
+	l.value = data&mask | l.value&^mask
+	for bit := uint(0); bit < 8; bit++ {
+		if mask&(1<<bit) != 0 {
+			l.refreshed[bit] = now
+		}
+	}
+}
+
+// Value returns the value held by the latch at time now after any decay
+func (l *DecayingLatch) Value(now uint64) uint8 {
+	for bit := uint(0); bit < 8; bit++ {
+		if l.value&(1<<bit) != 0 && now-l.refreshed[bit] >= l.decay {
+			l.value &^= 1 << bit
+		}
+	}
+	return l.value
+}
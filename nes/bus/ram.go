@@ -0,0 +1,22 @@
+package bus
+
+// RAM is a block of memory that can be mapped onto a bus. Addresses wrap around the size of the block
+type RAM []uint8
+
+// NewRAM returns a zeroed block of RAM of the given size
+func NewRAM(size uint16) RAM {
+	return make(RAM, size)
+}
+
+func (r RAM) Write(address uint16, data uint8) {
+	r[int(address)%len(r)] = data
+}
+
+func (r RAM) Read(address uint16) uint8 {
+	return r[int(address)%len(r)]
+}
+
+// Peek reads the RAM. Reading RAM has no side effects
+func (r RAM) Peek(address uint16) (uint8, uint8) {
+	return r.Read(address), 0xFF
+}
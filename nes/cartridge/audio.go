@@ -0,0 +1,19 @@
+package cartridge
+
+import "github.com/kevinwmiller/nesgo/nes/apu"
+
+// apuPulseLevel is the APU's output for one pulse channel at full volume. Chip volumes are measured against it
+const apuPulseLevel = 0.149
+
+// audioChip is implemented by mappers that have expansion audio
+type audioChip interface {
+	audio() apu.Expansion
+}
+
+// Audio returns the cartridge's expansion audio, or nil if it has none
+func (c *Cartridge) Audio() apu.Expansion {
+	if a, ok := c.mapper.(audioChip); ok {
+		return a.audio()
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package cartridge
+
+// bandai covers the Bandai FCG boards, mappers 16 and 159. Eight 1KB CHR banks, a switchable 16KB PRG bank, a 16 bit
+// CPU cycle IRQ counter and on the later LZ93D50 boards a serial EEPROM for saves. The FCG-1 and FCG-2 (submapper 4)
+// decode their registers at $6000-$7FFF and the LZ93D50 (submapper 5) at $8000-$FFFF. Mapper 159 is the LZ93D50 with
+// a 24C01.
+// http://wiki.nesdev.com/w/index.php/Bandai_FCG_board
+type bandai struct {
+	board
+	// The address ranges the registers respond at
+	fcg      bool
+	lz93d50  bool
+	prgBank  int
+	chrBanks [8]int
+
+	irqEnabled bool
+	irqCounter uint16
+	// The LZ93D50 writes the reload value to a latch that is copied to the counter when the IRQ is enabled. The FCG
+	// writes the counter directly. When the board is unknown both happen
+	irqLatch uint16
+
+	eeprom *eeprom
+}
+
+const (
+	bandaiPRGBankSize = 0x4000
+
+	// $800D. Bit 5 is SCL, bit 6 is SDA and bit 7 releases SDA so the EEPROM can drive it
+	bandaiSCL     uint8 = 0x20
+	bandaiSDA     uint8 = 0x40
+	bandaiSDARead uint8 = 0x80
+	// The bit the EEPROM's data line is read back on
+	bandaiEEPROMOut uint8 = 0x10
+)
+
+func newBandaiFCG(cart *Cartridge) Mapper {
+	m := newBandai(cart)
+	switch {
+	case cart.Format == NES20 && cart.Submapper == 4:
+		m.lz93d50 = false
+	case cart.Format == NES20 && cart.Submapper == 5:
+		m.fcg = false
+		if cart.PRGNVRAMSize > 0 {
+			m.eeprom = newEEPROM(eeprom24C02Size)
+		}
+	case cart.Battery:
+		m.eeprom = newEEPROM(eeprom24C02Size)
+	}
+	return m
+}
+
+func newBandai24C01(cart *Cartridge) Mapper {
+	m := newBandai(cart)
+	m.fcg = false
+	m.eeprom = newEEPROM(eeprom24C01Size)
+	return m
+}
+
+func newBandai(cart *Cartridge) *bandai {
+	m := &bandai{
+		board:   newBoard(cart),
+		fcg:     true,
+		lz93d50: true,
+	}
+	// The header describes the EEPROM as PRG NVRAM but there is no RAM on the CPU bus
+	m.prgRAM = nil
+	return m
+}
+
+// Tick clocks the IRQ counter, which raises an IRQ when it decrements to 0
+func (m *bandai) Tick() {
+	if !m.irqEnabled {
+		return
+	}
+	m.irqCounter--
+	if m.irqCounter == 0 {
+		m.setIRQ(true)
+	}
+}
+
+// batteryRAM returns the EEPROM contents, which are saved in place of PRG RAM
+func (m *bandai) batteryRAM() []uint8 {
+	if m.eeprom == nil {
+		return nil
+	}
+	return m.eeprom.data
+}
+
+func (m *bandai) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= 0xC000:
+		return m.prg[m.prgOffset(-1, bandaiPRGBankSize, address)], 0xFF
+	case address >= prgROMStart:
+		return m.prg[m.prgOffset(m.prgBank, bandaiPRGBankSize, address)], 0xFF
+	case address >= prgRAMStart && m.eeprom != nil:
+		if m.eeprom.read() {
+			return bandaiEEPROMOut, bandaiEEPROMOut
+		}
+		return 0, bandaiEEPROMOut
+	}
+	return 0, 0
+}
+
+func (m *bandai) WriteCPU(address uint16, data uint8) {
+	switch {
+	case address >= prgROMStart && m.lz93d50:
+		m.writeRegister(address, data)
+	case address >= prgRAMStart && address < prgROMStart && m.fcg:
+		m.writeRegister(address, data)
+	}
+}
+
+func (m *bandai) ReadPPU(address uint16) uint8 {
+	if address <= patternTableEnd {
+		return m.chr[m.chrOffset(m.chrBanks[address>>10], 0x0400, address)]
+	}
+	return m.readNametable(address)
+}
+
+func (m *bandai) WritePPU(address uint16, data uint8) {
+	if address <= patternTableEnd {
+		m.writeCHR(m.chrOffset(m.chrBanks[address>>10], 0x0400, address), data)
+		return
+	}
+	m.writeNametable(address, data)
+}
+
+func (m *bandai) writeRegister(address uint16, data uint8) {
+	switch register := address & 0x000F; {
+	case register < 0x08:
+		m.chrBanks[register] = int(data)
+	case register == 0x08:
+		m.prgBank = int(data & 0x0F)
+	case register == 0x09:
+		switch data & 0x03 {
+		case 0:
+			m.mirroring = Vertical
+		case 1:
+			m.mirroring = Horizontal
+		case 2:
+			m.mirroring = SingleScreenLower
+		case 3:
+			m.mirroring = SingleScreenUpper
+		}
+	case register == 0x0A:
+		m.irqEnabled = data&0x01 != 0
+		if m.lz93d50 {
+			m.irqCounter = m.irqLatch
+		}
+		m.setIRQ(false)
+	case register == 0x0B:
+		m.irqLatch = m.irqLatch&0xFF00 | uint16(data)
+		if m.fcg {
+			m.irqCounter = m.irqLatch
+		}
+	case register == 0x0C:
+		m.irqLatch = m.irqLatch&0x00FF | uint16(data)<<8
+		if m.fcg {
+			m.irqCounter = m.irqLatch
+		}
+	case register == 0x0D:
+		if m.eeprom != nil {
+			m.eeprom.write(data&bandaiSCL != 0, data&(bandaiSDA|bandaiSDARead) != 0)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package cartridge
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/kevinwmiller/nesgo/nes/bus"
+)
+
+// Cartridge holds the ROM and RAM contents of a game cartridge along with the header describing its hardware
+type Cartridge struct {
+	Header
+
+	PRG         []uint8
+	CHR         []uint8
+	TrainerData []uint8
+
+	mapper Mapper
+	// The mapper if it counts CPU cycles
+	ticker ticker
+}
+
+// Load reads and parses an iNES or NES 2.0 ROM file
+func Load(path string) (*Cartridge, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cart, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cart, nil
+}
+
+// Parse parses the contents of an iNES or NES 2.0 ROM file
+func Parse(data []uint8) (*Cartridge, error) {
+	header, err := parseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if header.PRGROMSize == 0 {
+		return nil, fmt.Errorf("%w: header declares no PRG ROM", ErrMalformed)
+	}
+
+	cart := &Cartridge{Header: header}
+	data = data[headerSize:]
+
+	if header.Trainer {
+		if len(data) < trainerSize {
+			return nil, fmt.Errorf("%w: header declares a trainer but the file ends after %d bytes", ErrMalformed, len(data))
+		}
+		cart.TrainerData = data[:trainerSize]
+		data = data[trainerSize:]
+	}
+
+	if len(data) < header.PRGROMSize {
+		return nil, fmt.Errorf("%w: header declares %dKB of PRG ROM but only %d bytes remain", ErrMalformed, header.PRGROMSize/1024, len(data))
+	}
+	cart.PRG = data[:header.PRGROMSize]
+	data = data[header.PRGROMSize:]
+
+	if len(data) < header.CHRROMSize {
+		return nil, fmt.Errorf("%w: header declares %dKB of CHR ROM but only %d bytes remain", ErrMalformed, header.CHRROMSize/1024, len(data))
+	}
+	cart.CHR = data[:header.CHRROMSize]
+
+	if cart.mapper, err = newMapper(cart); err != nil {
+		return nil, err
+	}
+	if cart.Trainer {
+		if err := cart.mapper.(trainerLoader).loadTrainer(cart.TrainerData); err != nil {
+			return nil, err
+		}
+	}
+	cart.ticker, _ = cart.mapper.(ticker)
+	return cart, nil
+}
+
+// String summarizes the cartridge hardware
+func (c *Cartridge) String() string {
+	return fmt.Sprintf("%s mapper %d.%d, %dKB PRG ROM, %dKB CHR ROM, %s mirroring, %s",
+		c.Format, c.Mapper, c.Submapper, c.PRGROMSize/1024, c.CHRROMSize/1024, c.Mirroring, c.Timing)
+}
+
+// ReadPartial reads from the cartridge space of the CPU bus, $4020-$FFFF
+func (c *Cartridge) ReadPartial(address uint16) (uint8, uint8) {
+	return c.mapper.ReadCPU(address)
+}
+
+// Peek reads from the cartridge space of the CPU bus, $4020-$FFFF, without any side effects on the mapper
+func (c *Cartridge) Peek(address uint16) (uint8, uint8) {
+	if p, ok := c.mapper.(cpuPeeker); ok {
+		return p.PeekCPU(address)
+	}
+	return c.mapper.ReadCPU(address)
+}
+
+// Read reads from the cartridge space of the CPU bus, $4020-$FFFF
+func (c *Cartridge) Read(address uint16) uint8 {
+	data, _ := c.mapper.ReadCPU(address)
+	return data
+}
+
+// Write writes to the cartridge space of the CPU bus, $4020-$FFFF
+func (c *Cartridge) Write(address uint16, data uint8) {
+	c.mapper.WriteCPU(address, data)
+}
+
+// Observe lets the mapper see CPU writes outside the cartridge space. The cartridge is attached to the PPU register
+// range as a bus.Observer so that boards like the MMC5 can follow PPUCTRL and PPUMASK
+func (c *Cartridge) Observe(address uint16, data uint8) {
+	if o, ok := c.mapper.(writeObserver); ok {
+		o.ObserveWrite(address, data)
+	}
+}
+
+// ConnectIRQ connects the cartridge to the CPU IRQ line so that the mapper can raise interrupts
+func (c *Cartridge) ConnectIRQ(irq IRQLine) {
+	if b, ok := c.mapper.(interface{ connectIRQ(IRQLine) }); ok {
+		b.connectIRQ(irq)
+	}
+}
+
+// EmulateBusConflicts turns bus conflicts on or off for boards that can have them. When enabled, values written to
+// ROM are ANDed with the ROM byte at the same address
+func (c *Cartridge) EmulateBusConflicts(enabled bool) {
+	if b, ok := c.mapper.(interface{ setBusConflicts(bool) }); ok {
+		b.setBusConflicts(enabled)
+	}
+}
+
+// Tick is called every CPU cycle and clocks any counters on the cartridge board
+func (c *Cartridge) Tick() {
+	if c.ticker != nil {
+		c.ticker.Tick()
+	}
+}
+
+// PPU returns the device to map into the pattern table and nametable space of the PPU bus, $0000-$3EFF
+func (c *Cartridge) PPU() bus.Device {
+	return ppuDevice{c.mapper}
+}
+
+// ppuDevice routes PPU bus accesses through the mapper
+type ppuDevice struct {
+	mapper Mapper
+}
+
+func (d ppuDevice) Read(address uint16) uint8 {
+	return d.mapper.ReadPPU(address)
+}
+
+func (d ppuDevice) Write(address uint16, data uint8) {
+	d.mapper.WritePPU(address, data)
+}
+
+// ObserveAddress lets the mapper see the PPU put address on the bus without reading or writing, for example when
+// the VRAM address is set through $2006
+func (d ppuDevice) ObserveAddress(address uint16) {
+	if o, ok := d.mapper.(addressObserver); ok {
+		o.ObserveAddress(address)
+	}
+}
@@ -0,0 +1,216 @@
+package cartridge
+
+// discrete covers the boards built from a latch and a few logic chips rather than an ASIC. Writing anywhere in
+// $8000-$FFFF stores a value in the latch which selects the PRG and CHR banks, and on AxROM the nametable.
+// http://wiki.nesdev.com/w/index.php/Category:Discrete_logic_mappers
+type discrete struct {
+	board
+	variant discreteVariant
+
+	// PRG banks mapped at $8000 and $C000 in 16KB units
+	prgBanks [2]int
+	// CHR banks mapped at $0000 and $1000 in 4KB units
+	chrBanks [2]int
+
+	// Boards where the latch is enabled by /ROMSEL let the ROM drive the data bus during the write. The CPU and ROM
+	// fight over the bus and the latch ends up with the AND of both values
+	busConflicts bool
+}
+
+type discreteVariant uint8
+
+const (
+	// Mapper 2. Switchable 16KB bank at $8000 with the last bank fixed at $C000
+	// http://wiki.nesdev.com/w/index.php/UxROM
+	uxrom discreteVariant = iota
+	// Mapper 3. Switchable 8KB CHR bank
+	// http://wiki.nesdev.com/w/index.php/CNROM
+	cnrom
+	// Mapper 7. Switchable 32KB PRG bank and single-screen mirroring selected by bit 4
+	// http://wiki.nesdev.com/w/index.php/AxROM
+	axrom
+	// Mapper 11. PRG bank in bits 0-1 and CHR bank in bits 4-7
+	// http://wiki.nesdev.com/w/index.php/Color_Dreams
+	colorDreams
+	// Mapper 34 submapper 2. Switchable 32KB PRG bank
+	// http://wiki.nesdev.com/w/index.php/BNROM
+	bnrom
+	// Mapper 34 submapper 1. Registers at $7FFD-$7FFF select a 32KB PRG bank and two 4KB CHR banks
+	// http://wiki.nesdev.com/w/index.php/NINA-001
+	nina001
+	// Mapper 66. PRG bank in bits 4-5 and CHR bank in bits 0-1
+	// http://wiki.nesdev.com/w/index.php/GxROM
+	gxrom
+)
+
+// submapperBusConflicts is the NES 2.0 submapper for mappers 2, 3 and 7 marking a board with bus conflicts
+const submapperBusConflicts uint8 = 2
+
+const (
+	discretePRGBankSize = 0x4000
+	discreteCHRBankSize = 0x1000
+
+	nina001PRG  uint16 = 0x7FFD
+	nina001CHR0 uint16 = 0x7FFE
+	nina001CHR1 uint16 = 0x7FFF
+)
+
+func newUxROM(cart *Cartridge) Mapper {
+	m := newDiscrete(cart, uxrom)
+	m.prgBanks = [2]int{0, -1}
+	return m
+}
+
+func newCNROM(cart *Cartridge) Mapper {
+	return newDiscrete(cart, cnrom)
+}
+
+func newAxROM(cart *Cartridge) Mapper {
+	m := newDiscrete(cart, axrom)
+	m.mirroring = SingleScreenLower
+	return m
+}
+
+func newColorDreams(cart *Cartridge) Mapper {
+	return newDiscrete(cart, colorDreams)
+}
+
+// newMapper34 returns BNROM or NINA-001, which share a mapper number. Without a submapper the boards are told apart by
+// NINA-001 being the only one with more than 8KB of CHR ROM
+func newMapper34(cart *Cartridge) Mapper {
+	variant := bnrom
+	switch {
+	case cart.Format == NES20 && cart.Submapper == 1:
+		variant = nina001
+	case cart.Format == NES20 && cart.Submapper == 2:
+		// BNROM
+	case len(cart.CHR) > 0x2000:
+		variant = nina001
+	}
+	m := newDiscrete(cart, variant)
+	if variant == nina001 && len(m.prgRAM) == 0 {
+		m.prgRAM = make([]uint8, int(prgRAMEnd-prgRAMStart)+1)
+	}
+	return m
+}
+
+func newGxROM(cart *Cartridge) Mapper {
+	return newDiscrete(cart, gxrom)
+}
+
+func newDiscrete(cart *Cartridge, variant discreteVariant) *discrete {
+	m := &discrete{
+		board:    newBoard(cart),
+		variant:  variant,
+		prgBanks: [2]int{0, 1},
+		chrBanks: [2]int{0, 1},
+	}
+	// Color Dreams and GxROM boards always have bus conflicts. For the others they are only emulated when the NES 2.0
+	// header says the board has them. Either can be overridden with EmulateBusConflicts
+	switch variant {
+	case colorDreams, gxrom:
+		m.busConflicts = true
+	case uxrom, cnrom, axrom:
+		m.busConflicts = cart.Format == NES20 && cart.Submapper == submapperBusConflicts
+	}
+	return m
+}
+
+// setBusConflicts turns bus conflict emulation on or off
+func (m *discrete) setBusConflicts(enabled bool) {
+	m.busConflicts = enabled && m.variant != nina001
+}
+
+func (m *discrete) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= prgROMStart:
+		bank := m.prgBanks[(address>>14)&0x01]
+		return m.prg[m.prgOffset(bank, discretePRGBankSize, address)], 0xFF
+	case address >= prgRAMStart:
+		return m.readPRGRAM(address)
+	}
+	return 0, 0
+}
+
+func (m *discrete) WriteCPU(address uint16, data uint8) {
+	switch {
+	case address >= prgROMStart:
+		if m.variant == nina001 {
+			return
+		}
+		if m.busConflicts {
+			rom, _ := m.ReadCPU(address)
+			data &= rom
+		}
+		m.writeLatch(data)
+	case address >= prgRAMStart:
+		m.writePRGRAM(address, data)
+		if m.variant == nina001 {
+			m.writeNINA001(address, data)
+		}
+	}
+}
+
+func (m *discrete) ReadPPU(address uint16) uint8 {
+	if address <= patternTableEnd {
+		return m.chr[m.chrOffset(m.chrBanks[address>>12], discreteCHRBankSize, address)]
+	}
+	return m.readNametable(address)
+}
+
+func (m *discrete) WritePPU(address uint16, data uint8) {
+	if address <= patternTableEnd {
+		m.writeCHR(m.chrOffset(m.chrBanks[address>>12], discreteCHRBankSize, address), data)
+		return
+	}
+	m.writeNametable(address, data)
+}
+
+// writeLatch decodes a value written to $8000-$FFFF into bank numbers. UxROM, CNROM and BNROM keep the unused high
+// bits and let them wrap around the size of the ROM so that oversized homebrew variants work. The other boards mask
+// the latch down to the lines their hardware decodes
+func (m *discrete) writeLatch(data uint8) {
+	switch m.variant {
+	case uxrom:
+		m.prgBanks[0] = int(data)
+	case cnrom:
+		m.selectCHR(int(data))
+	case axrom:
+		m.selectPRG(int(data & 0x07))
+		if data&0x10 != 0 {
+			m.mirroring = SingleScreenUpper
+		} else {
+			m.mirroring = SingleScreenLower
+		}
+	case colorDreams:
+		m.selectPRG(int(data & 0x03))
+		m.selectCHR(int(data >> 4))
+	case bnrom:
+		m.selectPRG(int(data))
+	case gxrom:
+		m.selectPRG(int(data>>4) & 0x03)
+		m.selectCHR(int(data & 0x03))
+	}
+}
+
+// writeNINA001 handles the NINA-001 registers, which sit on top of the PRG RAM at $7FFD-$7FFF
+func (m *discrete) writeNINA001(address uint16, data uint8) {
+	switch address {
+	case nina001PRG:
+		m.selectPRG(int(data & 0x01))
+	case nina001CHR0:
+		m.chrBanks[0] = int(data & 0x0F)
+	case nina001CHR1:
+		m.chrBanks[1] = int(data & 0x0F)
+	}
+}
+
+// selectPRG maps a 32KB PRG bank at $8000
+func (m *discrete) selectPRG(bank int) {
+	m.prgBanks = [2]int{bank * 2, bank*2 + 1}
+}
+
+// selectCHR maps an 8KB CHR bank at $0000
+func (m *discrete) selectCHR(bank int) {
+	m.chrBanks = [2]int{bank * 2, bank*2 + 1}
+}
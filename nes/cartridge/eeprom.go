@@ -0,0 +1,204 @@
+package cartridge
+
+// eeprom is a serial EEPROM on an I²C style bus, used by some Bandai boards to store saves instead of battery backed
+// RAM. The console bit-bangs the clock (SCL) and data (SDA) lines through a mapper register. The 24C02 holds 256 bytes
+// and uses the standard protocol with a device address byte, most significant bit first. The 24C01 on the Bandai
+// boards is the older X24C01, which holds 128 bytes and starts every transfer with its word address, least
+// significant bit first, with the read/write bit last.
+// http://wiki.nesdev.com/w/index.php/Bandai_FCG_board#Serial_EEPROM
+type eeprom struct {
+	data   []uint8
+	x24c01 bool
+
+	// The levels the console last drove on the lines
+	scl bool
+	sda bool
+	// The level the EEPROM drives on SDA. It only ever pulls the line low
+	out bool
+
+	state   eepromState
+	phase   eepromPhase
+	reading bool
+	address uint8
+	// The byte being shifted in or out and the number of bits shifted so far. bits reaches 9 while acknowledging
+	shift uint8
+	bits  int
+}
+
+type eepromState uint8
+
+const (
+	// Waiting for a start condition
+	eepromIdle eepromState = iota
+	// Receiving a byte from the console
+	eepromReceive
+	// Sending a byte to the console
+	eepromSend
+)
+
+type eepromPhase uint8
+
+const (
+	// Receiving the 24C02 device address and read/write bit
+	eepromDevice eepromPhase = iota
+	// Receiving the word address
+	eepromAddress
+	// Receiving data to write
+	eepromData
+)
+
+const (
+	eeprom24C01Size = 0x80
+	eeprom24C02Size = 0x100
+
+	eepromDeviceMask uint8 = 0xF0
+	eepromDeviceCode uint8 = 0xA0
+	// The number of bytes written in one transfer before the address wraps
+	eeprom24C01Page = 4
+	eeprom24C02Page = 8
+)
+
+func newEEPROM(size int) *eeprom {
+	return &eeprom{
+		data:   make([]uint8, size),
+		x24c01: size == eeprom24C01Size,
+		scl:    true,
+		sda:    true,
+		out:    true,
+	}
+}
+
+// write sets the levels of the clock and data lines. Start and stop conditions are SDA changing while SCL is high.
+// Otherwise data is sampled when SCL rises and changed while SCL is low
+func (e *eeprom) write(scl, sda bool) {
+	switch {
+	case e.scl && scl && e.sda && !sda:
+		e.start()
+	case e.scl && scl && !e.sda && sda:
+		e.state = eepromIdle
+		e.out = true
+	case !e.scl && scl:
+		e.rise(sda)
+	case e.scl && !scl:
+		e.fall()
+	}
+	e.scl, e.sda = scl, sda
+}
+
+// read returns the level of the data line as driven by the EEPROM
+func (e *eeprom) read() bool {
+	return e.out
+}
+
+func (e *eeprom) start() {
+	e.state = eepromReceive
+	e.phase = eepromDevice
+	if e.x24c01 {
+		e.phase = eepromAddress
+	}
+	e.reading = false
+	e.bits = 0
+	e.out = true
+}
+
+func (e *eeprom) rise(sda bool) {
+	var bit uint8
+	if sda {
+		bit = 1
+	}
+	switch e.state {
+	case eepromReceive:
+		if e.bits >= 8 {
+			return
+		}
+		if e.x24c01 {
+			e.shift = e.shift>>1 | bit<<7
+		} else {
+			e.shift = e.shift<<1 | bit
+		}
+		e.bits++
+	case eepromSend:
+		if e.bits < 8 {
+			e.bits++
+			return
+		}
+		// The ninth clock is the console acknowledging the byte. Without the acknowledgement the transfer ends
+		if sda {
+			e.state = eepromIdle
+			return
+		}
+		e.address = e.next(e.address, len(e.data))
+		e.bits = 0
+	}
+}
+
+func (e *eeprom) fall() {
+	switch e.state {
+	case eepromReceive:
+		switch e.bits {
+		case 8:
+			if !e.received(e.shift) {
+				e.state = eepromIdle
+				return
+			}
+			e.out = false
+			e.bits++
+		case 9:
+			e.out = true
+			e.bits = 0
+			if e.reading {
+				e.state = eepromSend
+				e.out = e.outputBit()
+			}
+		}
+	case eepromSend:
+		if e.bits < 8 {
+			e.out = e.outputBit()
+		} else {
+			e.out = true
+		}
+	}
+}
+
+// received handles a complete byte from the console and returns false if the EEPROM does not acknowledge it
+func (e *eeprom) received(data uint8) bool {
+	switch e.phase {
+	case eepromDevice:
+		if data&eepromDeviceMask != eepromDeviceCode {
+			return false
+		}
+		e.reading = data&0x01 != 0
+		e.phase = eepromAddress
+	case eepromAddress:
+		if e.x24c01 {
+			e.address = data & 0x7F
+			e.reading = data&0x80 != 0
+		} else {
+			e.address = data
+		}
+		e.phase = eepromData
+	case eepromData:
+		e.data[int(e.address)%len(e.data)] = data
+		page := eeprom24C02Page
+		if e.x24c01 {
+			page = eeprom24C01Page
+		}
+		e.address = e.next(e.address, page)
+	}
+	return true
+}
+
+// next returns the address after address, wrapping within a block of size bytes
+func (e *eeprom) next(address uint8, size int) uint8 {
+	block := int(address) &^ (size - 1)
+	return uint8(block | (int(address)+1)&(size-1))
+}
+
+// outputBit returns the next bit of the byte being sent
+func (e *eeprom) outputBit() bool {
+	data := e.data[int(e.address)%len(e.data)]
+	if e.x24c01 {
+		return data>>uint(e.bits)&0x01 != 0
+	}
+	return data>>uint(7-e.bits)&0x01 != 0
+}
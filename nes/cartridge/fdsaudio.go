@@ -0,0 +1,250 @@
+package cartridge
+
+// fdsAudio is the Famicom Disk System's sound hardware: a single channel playing a 64 step, 6 bit wavetable, with a
+// volume envelope and a second wavetable that modulates the pitch. The FDS RAM adapter is not a cartridge board, so
+// the chip is kept separate from any mapper for whatever hosts it.
+// http://wiki.nesdev.com/w/index.php/FDS_audio
+type fdsAudio struct {
+	wave [fdsWaveSize]uint8
+	// $4089 bit 7. The wavetable can only be written while it is set, and the output holds its last value meanwhile
+	waveWrite    bool
+	masterVolume uint8
+
+	frequency    uint16
+	waveHalt     bool
+	envelopeHalt bool
+	// The position in the wavetable is the upper 6 bits of a 22 bit accumulator
+	wavePhase uint32
+	output    uint8
+
+	volume     fdsEnvelope
+	modulation fdsEnvelope
+	// $408A, a multiplier for both envelope periods
+	envelopeSpeed uint8
+
+	modTable     [fdsWaveSize]uint8
+	modPosition  uint8
+	modFrequency uint16
+	modHalt      bool
+	// The 7 bit signed sweep bias that the modulation table adjusts
+	modCounter int
+	modPhase   uint16
+}
+
+// fdsEnvelope is one of the two envelope units, which step a gain up or down by one at a fixed rate
+type fdsEnvelope struct {
+	// $4080 or $4084. Bit 7 disables the envelope, bit 6 sets its direction and bits 0-5 are its speed
+	control uint8
+	gain    uint8
+	timer   int
+}
+
+// FDS audio registers
+const (
+	fdsWaveStart    uint16 = 0x4040
+	fdsWaveEnd      uint16 = 0x407F
+	fdsVolumeEnv    uint16 = 0x4080
+	fdsFreqLow      uint16 = 0x4082
+	fdsFreqHigh     uint16 = 0x4083
+	fdsModEnv       uint16 = 0x4084
+	fdsModCounter   uint16 = 0x4085
+	fdsModFreqLow   uint16 = 0x4086
+	fdsModFreqHigh  uint16 = 0x4087
+	fdsModTable     uint16 = 0x4088
+	fdsMaster       uint16 = 0x4089
+	fdsEnvSpeed     uint16 = 0x408A
+	fdsVolumeGain   uint16 = 0x4090
+	fdsModGain      uint16 = 0x4092
+	fdsRegistersEnd uint16 = 0x4092
+)
+
+const (
+	fdsWaveSize           = 64
+	fdsMaxGain            = 32
+	fdsEnvelopeDisable    = 0x80
+	fdsEnvelopeIncrease   = 0x40
+	fdsHalt               = 0x80
+	fdsHaltEnvelopes      = 0x40
+	fdsDefaultEnvelope    = 0xE8
+	fdsModTableReset      = 4
+	fdsWavePhaseMask      = 0x3FFFFF
+	fdsEnvelopeMultiplier = 8
+	// The full scale output, wave sample 63 at gain 32
+	fdsMaxOutput = 63 * fdsMaxGain
+	// At full volume the FDS is about two and a half times as loud as an APU pulse at full volume
+	fdsVolume = apuPulseLevel * 2.4
+)
+
+// fdsModSteps is how much each modulation table entry adds to the counter. Entry 4 resets it to 0 instead
+var fdsModSteps = [8]int{0, 1, 2, 4, 0, -4, -2, -1}
+
+// fdsMasterVolumes are the output levels selected by $4089 bits 0-1
+var fdsMasterVolumes = [4]float32{2.0 / 2, 2.0 / 3, 2.0 / 4, 2.0 / 5}
+
+func newFDSAudio() fdsAudio {
+	return fdsAudio{envelopeSpeed: fdsDefaultEnvelope}
+}
+
+// read reads the wavetable or one of the gain registers. Only the low 6 bits are driven
+func (a *fdsAudio) read(address uint16) (uint8, uint8) {
+	switch {
+	case address >= fdsWaveStart && address <= fdsWaveEnd:
+		return a.wave[address-fdsWaveStart], 0x3F
+	case address == fdsVolumeGain:
+		return a.volume.gain, 0x3F
+	case address == fdsModGain:
+		return a.modulation.gain, 0x3F
+	}
+	return 0, 0
+}
+
+// write writes one of the registers at $4040-$408A
+func (a *fdsAudio) write(address uint16, data uint8) {
+	switch address {
+	case fdsVolumeEnv:
+		a.volume.write(data)
+	case fdsFreqLow:
+		a.frequency = a.frequency&0x0F00 | uint16(data)
+	case fdsFreqHigh:
+		a.frequency = a.frequency&0x00FF | uint16(data&0x0F)<<8
+		a.waveHalt = data&fdsHalt != 0
+		a.envelopeHalt = data&fdsHaltEnvelopes != 0
+		if a.waveHalt {
+			a.wavePhase = 0
+		}
+	case fdsModEnv:
+		a.modulation.write(data)
+	case fdsModCounter:
+		a.modCounter = int(int8(data<<1) >> 1)
+	case fdsModFreqLow:
+		a.modFrequency = a.modFrequency&0x0F00 | uint16(data)
+	case fdsModFreqHigh:
+		a.modFrequency = a.modFrequency&0x00FF | uint16(data&0x0F)<<8
+		a.modHalt = data&fdsHalt != 0
+	case fdsModTable:
+		// Each write fills two consecutive entries, so the table is effectively 32 entries long
+		if a.modHalt {
+			a.modTable[a.modPosition] = data & 0x07
+			a.modTable[a.modPosition+1] = data & 0x07
+			a.modPosition = (a.modPosition + 2) % fdsWaveSize
+		}
+	case fdsMaster:
+		a.waveWrite = data&fdsHalt != 0
+		a.masterVolume = data & 0x03
+	case fdsEnvSpeed:
+		a.envelopeSpeed = data
+	default:
+		if address >= fdsWaveStart && address <= fdsWaveEnd && a.waveWrite {
+			a.wave[address-fdsWaveStart] = data & 0x3F
+		}
+	}
+}
+
+// write writes the envelope's control register. With the envelope disabled, the speed is the gain
+func (e *fdsEnvelope) write(data uint8) {
+	e.control = data
+	e.timer = 0
+	if data&fdsEnvelopeDisable != 0 {
+		e.gain = data & 0x3F
+	}
+}
+
+// tick counts down the envelope's period, which is 8 * (master speed + 1) * (speed + 1) CPU cycles
+func (e *fdsEnvelope) tick(speed uint8) {
+	if e.control&fdsEnvelopeDisable != 0 {
+		return
+	}
+	if e.timer > 0 {
+		e.timer--
+		return
+	}
+	e.timer = fdsEnvelopeMultiplier*(int(speed)+1)*(int(e.control&0x3F)+1) - 1
+	if e.control&fdsEnvelopeIncrease != 0 {
+		if e.gain < fdsMaxGain {
+			e.gain++
+		}
+	} else if e.gain > 0 {
+		e.gain--
+	}
+}
+
+// tick is called every CPU cycle
+func (a *fdsAudio) tick() {
+	if !a.envelopeHalt && !a.waveHalt && a.envelopeSpeed != 0 {
+		a.volume.tick(a.envelopeSpeed)
+		a.modulation.tick(a.envelopeSpeed)
+	}
+
+	if !a.modHalt && a.modFrequency != 0 {
+		phase := a.modPhase + a.modFrequency
+		if phase < a.modPhase {
+			a.stepModulation()
+		}
+		a.modPhase = phase
+	}
+
+	if a.waveWrite {
+		return
+	}
+	if !a.waveHalt {
+		a.wavePhase = (a.wavePhase + a.pitch()) & fdsWavePhaseMask
+	}
+	a.output = a.wave[a.wavePhase>>16]
+}
+
+// stepModulation applies the next modulation table entry to the counter, which wraps around its 7 bit range
+func (a *fdsAudio) stepModulation() {
+	step := a.modTable[a.modPosition]
+	if step == fdsModTableReset {
+		a.modCounter = 0
+	} else {
+		a.modCounter = (a.modCounter+fdsModSteps[step]+64)&0x7F - 64
+	}
+	a.modPosition = (a.modPosition + 1) % fdsWaveSize
+}
+
+// pitch returns the wave frequency adjusted by the modulator. This follows the chip's integer arithmetic, rounding
+// quirks included
+func (a *fdsAudio) pitch() uint32 {
+	frequency := int(a.frequency)
+	if a.modHalt {
+		return uint32(frequency)
+	}
+	temp := a.modCounter * int(a.modulation.gain)
+	remainder := temp & 0x0F
+	temp >>= 4
+	if remainder > 0 && temp&0x80 == 0 {
+		if a.modCounter < 0 {
+			temp--
+		} else {
+			temp += 2
+		}
+	}
+	if temp >= 192 {
+		temp -= 256
+	} else if temp < -64 {
+		temp += 256
+	}
+	temp *= frequency
+	remainder = temp & 0x3F
+	temp >>= 6
+	if remainder >= 32 {
+		temp++
+	}
+	if frequency+temp < 0 {
+		return 0
+	}
+	return uint32(frequency + temp)
+}
+
+func (a *fdsAudio) Sample() float32 {
+	gain := a.volume.gain
+	if gain > fdsMaxGain {
+		gain = fdsMaxGain
+	}
+	return float32(int(a.output)*int(gain)) / fdsMaxOutput * fdsMasterVolumes[a.masterVolume]
+}
+
+func (a *fdsAudio) Volume() float32 {
+	return fdsVolume
+}
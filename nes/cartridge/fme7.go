@@ -0,0 +1,127 @@
+package cartridge
+
+import "github.com/kevinwmiller/nesgo/nes/apu"
+
+// fme7 is the Sunsoft FME-7 and its 5B variant, mapper 69. Four switchable 8KB PRG banks, one of which can be PRG RAM,
+// eight 1KB CHR banks, a 16 bit CPU cycle IRQ counter and, on the 5B, three square wave sound channels.
+// http://wiki.nesdev.com/w/index.php/Sunsoft_FME-7
+type fme7 struct {
+	board
+
+	// The register selected by $8000
+	command uint8
+
+	prgBanks [4]int
+	chrBanks [8]int
+	// Register 8, which selects what is mapped at $6000-$7FFF
+	prgRAMControl uint8
+
+	irqCounter        uint16
+	irqEnabled        bool
+	irqCounterEnabled bool
+
+	sound sunsoft5B
+}
+
+const (
+	fme7PRGRAMSelect uint8 = 0x40
+	fme7PRGRAMEnable uint8 = 0x80
+
+	fme7IRQEnable        uint8 = 0x01
+	fme7IRQCounterEnable uint8 = 0x80
+)
+
+func newFME7(cart *Cartridge) Mapper {
+	return &fme7{
+		board: newBoard(cart),
+		sound: newSunsoft5B(),
+	}
+}
+
+// Tick clocks the IRQ counter and the sound channels. The IRQ fires when the counter wraps from $0000 to $FFFF
+func (m *fme7) Tick() {
+	if m.irqCounterEnabled {
+		m.irqCounter--
+		if m.irqCounter == 0xFFFF && m.irqEnabled {
+			m.setIRQ(true)
+		}
+	}
+	m.sound.tick()
+}
+
+func (m *fme7) audio() apu.Expansion {
+	return &m.sound
+}
+
+func (m *fme7) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= 0xE000:
+		return m.prg[m.prgOffset(-1, 0x2000, address)], 0xFF
+	case address >= prgROMStart:
+		return m.prg[m.prgOffset(m.prgBanks[(address-prgROMStart)>>13+1], 0x2000, address)], 0xFF
+	case address >= prgRAMStart:
+		if m.prgRAMControl&fme7PRGRAMSelect == 0 {
+			return m.prg[m.prgOffset(m.prgBanks[0], 0x2000, address)], 0xFF
+		}
+		if m.prgRAMControl&fme7PRGRAMEnable == 0 {
+			return 0, 0
+		}
+		return m.readPRGRAM(address)
+	}
+	return 0, 0
+}
+
+func (m *fme7) WriteCPU(address uint16, data uint8) {
+	switch {
+	case address >= 0xE000:
+		m.sound.writeData(data)
+	case address >= 0xC000:
+		m.sound.writeAddress(data)
+	case address >= 0xA000:
+		m.writeRegister(data)
+	case address >= prgROMStart:
+		m.command = data & 0x0F
+	case address >= prgRAMStart:
+		if m.prgRAMControl&(fme7PRGRAMSelect|fme7PRGRAMEnable) == fme7PRGRAMSelect|fme7PRGRAMEnable {
+			m.writePRGRAM(address, data)
+		}
+	}
+}
+
+func (m *fme7) ReadPPU(address uint16) uint8 {
+	if address <= patternTableEnd {
+		return m.chr[m.chrOffset(m.chrBanks[address>>10], 0x0400, address)]
+	}
+	return m.readNametable(address)
+}
+
+func (m *fme7) WritePPU(address uint16, data uint8) {
+	if address <= patternTableEnd {
+		m.writeCHR(m.chrOffset(m.chrBanks[address>>10], 0x0400, address), data)
+		return
+	}
+	m.writeNametable(address, data)
+}
+
+// writeRegister writes the register selected by the last command
+func (m *fme7) writeRegister(data uint8) {
+	switch command := m.command; {
+	case command <= 0x07:
+		m.chrBanks[command] = int(data)
+	case command == 0x08:
+		m.prgRAMControl = data
+		m.prgBanks[0] = int(data & 0x3F)
+	case command <= 0x0B:
+		m.prgBanks[command-0x08] = int(data & 0x3F)
+	case command == 0x0C:
+		m.mirroring = [...]Mirroring{Vertical, Horizontal, SingleScreenLower, SingleScreenUpper}[data&0x03]
+	case command == 0x0D:
+		m.irqEnabled = data&fme7IRQEnable != 0
+		m.irqCounterEnabled = data&fme7IRQCounterEnable != 0
+		m.setIRQ(false)
+	case command == 0x0E:
+		m.irqCounter = m.irqCounter&0xFF00 | uint16(data)
+	case command == 0x0F:
+		m.irqCounter = m.irqCounter&0x00FF | uint16(data)<<8
+	}
+}
@@ -0,0 +1,241 @@
+package cartridge
+
+import (
+	"errors"
+	"fmt"
+)
+
+// iNES and NES 2.0 header layout. http://wiki.nesdev.com/w/index.php/INES and http://wiki.nesdev.com/w/index.php/NES_2.0
+const (
+	headerSize  = 16
+	trainerSize = 512
+	prgROMUnit  = 0x4000
+	chrROMUnit  = 0x2000
+	// iNES 1.0 PRG RAM sizes are given in 8KB units
+	prgRAMUnit = 0x2000
+)
+
+var headerMagic = [4]uint8{'N', 'E', 'S', 0x1A}
+
+// Flags 6
+const (
+	flagVerticalMirroring uint8 = 1 << iota
+	flagBattery
+	flagTrainer
+	flagFourScreen
+)
+
+// Errors returned while parsing a ROM. They are wrapped with details about the specific problem
+var (
+	ErrNotINES   = errors.New("not an iNES file")
+	ErrMalformed = errors.New("malformed ROM")
+)
+
+// Format is the version of the header a ROM was stored with
+type Format uint8
+
+// Header formats
+const (
+	INES Format = iota
+	NES20
+)
+
+func (f Format) String() string {
+	if f == NES20 {
+		return "NES 2.0"
+	}
+	return "iNES"
+}
+
+// Mirroring describes how the two physical nametables in the console are arranged in the four logical nametables
+type Mirroring uint8
+
+// Nametable mirroring arrangements
+const (
+	Horizontal Mirroring = iota
+	Vertical
+	FourScreen
+	SingleScreenLower
+	SingleScreenUpper
+)
+
+func (m Mirroring) String() string {
+	switch m {
+	case Horizontal:
+		return "horizontal"
+	case Vertical:
+		return "vertical"
+	case FourScreen:
+		return "four-screen"
+	case SingleScreenLower:
+		return "single-screen lower"
+	case SingleScreenUpper:
+		return "single-screen upper"
+	}
+	return fmt.Sprintf("Mirroring(%d)", m)
+}
+
+// ConsoleType is the kind of system the ROM was made for
+type ConsoleType uint8
+
+// Console types
+const (
+	NESConsole ConsoleType = iota
+	VsSystem
+	Playchoice10
+	ExtendedConsole
+)
+
+// Timing is the CPU/PPU timing region the ROM expects
+type Timing uint8
+
+// Timing regions
+const (
+	NTSC Timing = iota
+	PAL
+	MultiRegion
+	Dendy
+)
+
+func (t Timing) String() string {
+	switch t {
+	case NTSC:
+		return "NTSC"
+	case PAL:
+		return "PAL"
+	case MultiRegion:
+		return "multi-region"
+	case Dendy:
+		return "Dendy"
+	}
+	return fmt.Sprintf("Timing(%d)", t)
+}
+
+// Header describes the contents of a ROM file. All sizes are in bytes
+type Header struct {
+	Format    Format
+	Mapper    uint16
+	Submapper uint8
+	Mirroring Mirroring
+	// The cartridge contains battery backed memory that keeps its contents while the console is off
+	Battery bool
+	// A 512 byte trainer is stored before the PRG ROM and is loaded at $7000
+	Trainer bool
+
+	PRGROMSize   int
+	CHRROMSize   int
+	PRGRAMSize   int
+	PRGNVRAMSize int
+	CHRRAMSize   int
+	CHRNVRAMSize int
+
+	ConsoleType ConsoleType
+	Timing      Timing
+}
+
+// parseHeader decodes a 16 byte iNES or NES 2.0 header
+func parseHeader(data []uint8) (Header, error) {
+	var h Header
+	if len(data) < headerSize {
+		return h, fmt.Errorf("%w: file is only %d bytes, shorter than the %d byte header", ErrNotINES, len(data), headerSize)
+	}
+	if [4]uint8{data[0], data[1], data[2], data[3]} != headerMagic {
+		return h, fmt.Errorf("%w: missing NES<EOF> signature", ErrNotINES)
+	}
+
+	flags6 := data[6]
+	flags7 := data[7]
+
+	switch {
+	case flags6&flagFourScreen != 0:
+		h.Mirroring = FourScreen
+	case flags6&flagVerticalMirroring != 0:
+		h.Mirroring = Vertical
+	default:
+		h.Mirroring = Horizontal
+	}
+	h.Battery = flags6&flagBattery != 0
+	h.Trainer = flags6&flagTrainer != 0
+	h.ConsoleType = ConsoleType(flags7 & 0x03)
+	h.Mapper = uint16(flags6>>4) | uint16(flags7&0xF0)
+
+	if flags7&0x0C == 0x08 {
+		return h, h.parseNES20(data)
+	}
+	h.parseINES(data)
+	return h, nil
+}
+
+// parseNES20 decodes the NES 2.0 extensions in bytes 8 to 15
+func (h *Header) parseNES20(data []uint8) error {
+	h.Format = NES20
+	h.Mapper |= uint16(data[8]&0x0F) << 8
+	h.Submapper = data[8] >> 4
+
+	var err error
+	if h.PRGROMSize, err = romSize(data[4], data[9]&0x0F, prgROMUnit); err != nil {
+		return fmt.Errorf("PRG ROM: %w", err)
+	}
+	if h.CHRROMSize, err = romSize(data[5], data[9]>>4, chrROMUnit); err != nil {
+		return fmt.Errorf("CHR ROM: %w", err)
+	}
+	h.PRGRAMSize = shiftSize(data[10] & 0x0F)
+	h.PRGNVRAMSize = shiftSize(data[10] >> 4)
+	h.CHRRAMSize = shiftSize(data[11] & 0x0F)
+	h.CHRNVRAMSize = shiftSize(data[11] >> 4)
+	h.Timing = Timing(data[12] & 0x03)
+	return nil
+}
+
+// parseINES decodes the archaic iNES 1.0 fields in bytes 8 to 15
+func (h *Header) parseINES(data []uint8) {
+	h.Format = INES
+	h.PRGROMSize = int(data[4]) * prgROMUnit
+	h.CHRROMSize = int(data[5]) * chrROMUnit
+
+	// Some old dumping tools wrote a signature such as "DiskDude!" over bytes 7 to 15. If the unused bytes are not zero
+	// the upper nibble of the mapper number cannot be trusted either
+	if data[12] != 0 || data[13] != 0 || data[14] != 0 || data[15] != 0 {
+		h.Mapper &= 0x0F
+		h.ConsoleType = NESConsole
+		return
+	}
+
+	// A PRG RAM size of 0 means 8KB for compatibility with ROMs that predate the field
+	h.PRGRAMSize = int(data[8]) * prgRAMUnit
+	if h.PRGRAMSize == 0 {
+		h.PRGRAMSize = prgRAMUnit
+	}
+	if h.Battery {
+		h.PRGNVRAMSize = h.PRGRAMSize
+		h.PRGRAMSize = 0
+	}
+	if h.CHRROMSize == 0 {
+		h.CHRRAMSize = chrROMUnit
+	}
+	if data[9]&0x01 != 0 {
+		h.Timing = PAL
+	}
+}
+
+// romSize decodes a NES 2.0 ROM size from its least significant byte and most significant nibble. When the nibble is
+// $F the size uses exponent-multiplier notation, 2^E * (MM*2+1), with the LSB laid out as EEEEEEMM
+func romSize(lsb, msb uint8, unit int) (int, error) {
+	if msb != 0x0F {
+		return (int(msb)<<8 | int(lsb)) * unit, nil
+	}
+	exponent := uint(lsb >> 2)
+	multiplier := int(lsb&0x03)*2 + 1
+	if exponent > 30 {
+		return 0, fmt.Errorf("%w: size exponent %d is too large", ErrMalformed, exponent)
+	}
+	return (1 << exponent) * multiplier, nil
+}
+
+// shiftSize decodes a NES 2.0 RAM size, stored as a shift count of 64 bytes. A count of 0 means no RAM
+func shiftSize(shift uint8) int {
+	if shift == 0 {
+		return 0
+	}
+	return 64 << shift
+}
@@ -0,0 +1,252 @@
+package cartridge
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedMapper is returned when a ROM uses a board that has not been implemented
+var ErrUnsupportedMapper = errors.New("unsupported mapper")
+
+// Mapper is the circuitry on a cartridge board that decodes the CPU and PPU address buses.
+// http://wiki.nesdev.com/w/index.php/Mapper
+type Mapper interface {
+	// ReadCPU reads from the cartridge space of the CPU bus, $4020-$FFFF. driven is a mask of the data lines the
+	// cartridge drove. The other bits are left to open bus
+	ReadCPU(address uint16) (data, driven uint8)
+	// WriteCPU writes to the cartridge space of the CPU bus, $4020-$FFFF
+	WriteCPU(address uint16, data uint8)
+	// ReadPPU reads from the pattern tables and nametables on the PPU bus, $0000-$3EFF
+	ReadPPU(address uint16) uint8
+	// WritePPU writes to the pattern tables and nametables on the PPU bus, $0000-$3EFF
+	WritePPU(address uint16, data uint8)
+}
+
+// cpuPeeker is implemented by mappers whose CPU reads have side effects, like acknowledging an IRQ. PeekCPU returns
+// what ReadCPU would without them. Other mappers are peeked through ReadCPU
+type cpuPeeker interface {
+	PeekCPU(address uint16) (data, driven uint8)
+}
+
+// trainerLoader is implemented by every mapper through its board
+type trainerLoader interface {
+	loadTrainer(trainer []uint8) error
+}
+
+// ticker is implemented by mappers that need to count CPU cycles
+type ticker interface {
+	Tick()
+}
+
+// addressObserver is implemented by mappers that watch the PPU address bus, even when the PPU is not reading or
+// writing through it
+type addressObserver interface {
+	ObserveAddress(address uint16)
+}
+
+// writeObserver is implemented by mappers that snoop CPU writes outside the cartridge space
+type writeObserver interface {
+	ObserveWrite(address uint16, data uint8)
+}
+
+// IRQLine is how a cartridge asserts and releases the CPU IRQ line
+type IRQLine func(asserted bool)
+
+// mapperConstructors creates the mapper for each supported iNES mapper number
+var mapperConstructors = map[uint16]func(*Cartridge) Mapper{
+	0:   newNROM,
+	1:   newMMC1,
+	2:   newUxROM,
+	3:   newCNROM,
+	4:   newMMC3,
+	5:   newMMC5,
+	7:   newAxROM,
+	11:  newColorDreams,
+	16:  newBandaiFCG,
+	19:  newN163,
+	21:  newVRC4Mapper21,
+	22:  newVRC2Mapper22,
+	23:  newVRC24Mapper23,
+	24:  newVRC6a,
+	25:  newVRC24Mapper25,
+	26:  newVRC6b,
+	34:  newMapper34,
+	66:  newGxROM,
+	69:  newFME7,
+	85:  newVRC7,
+	159: newBandai24C01,
+}
+
+// newMapper returns the mapper the cartridge header asks for
+func newMapper(cart *Cartridge) (Mapper, error) {
+	constructor, ok := mapperConstructors[cart.Mapper]
+	if !ok {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedMapper, cart.Mapper)
+	}
+	return constructor(cart), nil
+}
+
+// Common CPU and PPU address ranges decoded by mappers
+const (
+	prgRAMStart uint16 = 0x6000
+	prgRAMEnd   uint16 = 0x7FFF
+	prgROMStart uint16 = 0x8000
+
+	patternTableEnd uint16 = 0x1FFF
+	nametableStart  uint16 = 0x2000
+
+	trainerAddress uint16 = 0x7000
+	defaultCHRRAM         = 0x2000
+)
+
+// board holds the memory that every mapper works with: PRG ROM, PRG RAM, CHR ROM or RAM and the nametables.
+// Mappers embed a board and only implement their own bank switching on top of it
+type board struct {
+	prg    []uint8
+	prgRAM []uint8
+	chr    []uint8
+	// chr is CHR RAM rather than CHR ROM
+	chrWritable bool
+
+	nametables
+
+	irq IRQLine
+}
+
+// newBoard allocates the RAM described by the cartridge header
+func newBoard(cart *Cartridge) board {
+	b := board{
+		prg: cart.PRG,
+		chr: cart.CHR,
+	}
+	b.mirroring = cart.Mirroring
+
+	size := cart.PRGRAMSize + cart.PRGNVRAMSize
+	if cart.Trainer && size < trainerSize {
+		// The trainer is loaded into PRG RAM at $7000, so a board that declares too little still needs it
+		size = int(prgRAMEnd-prgRAMStart) + 1
+	}
+	if size > 0 {
+		b.prgRAM = make([]uint8, size)
+	}
+
+	if len(b.chr) == 0 {
+		size := cart.CHRRAMSize + cart.CHRNVRAMSize
+		if size == 0 {
+			size = defaultCHRRAM
+		}
+		b.chr = make([]uint8, size)
+		b.chrWritable = true
+	}
+	return b
+}
+
+// prgOffset returns the offset into PRG ROM of address within bank, where banks are size bytes long. Bank numbers
+// wrap around the amount of PRG ROM, and negative bank numbers count back from the last bank
+func (b *board) prgOffset(bank, size int, address uint16) int {
+	return bankOffset(len(b.prg), bank, size, address)
+}
+
+// chrOffset returns the offset into CHR memory of address within bank, where banks are size bytes long
+func (b *board) chrOffset(bank, size int, address uint16) int {
+	return bankOffset(len(b.chr), bank, size, address)
+}
+
+func bankOffset(total, bank, size int, address uint16) int {
+	banks := total / size
+	if banks == 0 {
+		return int(address) % total
+	}
+	bank %= banks
+	if bank < 0 {
+		bank += banks
+	}
+	return bank*size + int(address)%size
+}
+
+// loadTrainer copies a trainer into the PRG RAM at $7000. It fails when the mapper has no PRG RAM to hold it
+func (b *board) loadTrainer(trainer []uint8) error {
+	if len(b.prgRAM) == 0 {
+		return fmt.Errorf("%w: header declares a trainer but the board has no PRG RAM at $%04X", ErrMalformed, trainerAddress)
+	}
+	offset := int(trainerAddress-prgRAMStart) % len(b.prgRAM)
+	if offset+len(trainer) > len(b.prgRAM) {
+		return fmt.Errorf("%w: header declares a trainer but the board's %d bytes of PRG RAM cannot hold it at $%04X",
+			ErrMalformed, len(b.prgRAM), trainerAddress)
+	}
+	copy(b.prgRAM[offset:], trainer)
+	return nil
+}
+
+// readPRGRAM reads from the PRG RAM at $6000-$7FFF. If the board has no PRG RAM nothing drives the bus
+func (b *board) readPRGRAM(address uint16) (uint8, uint8) {
+	if len(b.prgRAM) == 0 {
+		return 0, 0
+	}
+	return b.prgRAM[int(address-prgRAMStart)%len(b.prgRAM)], 0xFF
+}
+
+// writePRGRAM writes to the PRG RAM at $6000-$7FFF
+func (b *board) writePRGRAM(address uint16, data uint8) {
+	if len(b.prgRAM) == 0 {
+		return
+	}
+	b.prgRAM[int(address-prgRAMStart)%len(b.prgRAM)] = data
+}
+
+// connectIRQ connects the board to the CPU IRQ line
+func (b *board) connectIRQ(irq IRQLine) {
+	b.irq = irq
+}
+
+// setIRQ asserts or releases the CPU IRQ line
+func (b *board) setIRQ(asserted bool) {
+	if b.irq != nil {
+		b.irq(asserted)
+	}
+}
+
+// batteryRAM returns the memory kept by the battery. Boards that save somewhere other than PRG RAM override it
+func (b *board) batteryRAM() []uint8 {
+	return b.prgRAM
+}
+
+// writeCHR writes to CHR memory if it is RAM
+func (b *board) writeCHR(offset int, data uint8) {
+	if b.chrWritable {
+		b.chr[offset] = data
+	}
+}
+
+// nametables maps the four logical nametables at $2000-$2FFF onto physical memory. The console has 2KB of VRAM,
+// enough for two nametables. The cartridge controls how they are arranged, or can provide two more of its own for
+// four-screen mirroring
+type nametables struct {
+	vram      [0x1000]uint8
+	mirroring Mirroring
+}
+
+// nametableOffset returns the offset into VRAM of a nametable address
+func (n *nametables) nametableOffset(address uint16) int {
+	table := int(address>>10) & 0x03
+	offset := int(address) & 0x03FF
+	switch n.mirroring {
+	case Horizontal:
+		table >>= 1
+	case Vertical:
+		table &= 0x01
+	case SingleScreenLower:
+		table = 0
+	case SingleScreenUpper:
+		table = 1
+	}
+	return table<<10 | offset
+}
+
+func (n *nametables) readNametable(address uint16) uint8 {
+	return n.vram[n.nametableOffset(address)]
+}
+
+func (n *nametables) writeNametable(address uint16, data uint8) {
+	n.vram[n.nametableOffset(address)] = data
+}
@@ -0,0 +1,266 @@
+package cartridge
+
+// mmc1 is mapper 1, used by the SxROM boards. Registers are loaded serially through a 5 bit shift register, one bit
+// per write to $8000-$FFFF. http://wiki.nesdev.com/w/index.php/MMC1
+type mmc1 struct {
+	board
+	variant mmc1Variant
+
+	shift      uint8
+	shiftCount uint8
+
+	control  uint8
+	chrBank0 uint8
+	chrBank1 uint8
+	prgBank  uint8
+
+	// The MMC1 ignores a write on the cycle immediately after another write, which happens when a read-modify-write
+	// instruction writes the old and then the new value
+	cycle     uint64
+	lastWrite uint64
+
+	// The A12 line of the last pattern table fetch. In 4KB CHR mode it selects which CHR bank register drives the
+	// extra PRG and PRG RAM lines on SOROM, SUROM and SXROM
+	chrA12 bool
+}
+
+// Boards built around the MMC1 repurpose the upper CHR bank bits for larger PRG ROM and PRG RAM
+type mmc1Variant uint8
+
+const (
+	// SKROM, SLROM and friends. CHR bank bits only select CHR
+	mmc1Standard mmc1Variant = iota
+	// SNROM uses bit 4 of the CHR bank as an extra PRG RAM disable
+	snrom
+	// SOROM uses bit 3 of the CHR bank to select one of two 8KB PRG RAM banks
+	sorom
+	// SUROM uses bit 4 of the CHR bank to select the 256KB half of a 512KB PRG ROM
+	surom
+	// SXROM combines SUROM's PRG selection with bits 2-3 selecting one of four 8KB PRG RAM banks
+	sxrom
+	// SEROM, SHROM and SH1ROM wire the PRG ROM directly so it cannot be switched. NES 2.0 submapper 5
+	serom
+)
+
+const (
+	mmc1ShiftReset   uint8 = 0x80
+	mmc1PRGModeFixed uint8 = 0x0C
+	mmc1PRGRAMOff    uint8 = 0x10
+	mmc1CHR4KB       uint8 = 0x10
+
+	mmc1PRGBankSize = 0x4000
+	mmc1CHRBankSize = 0x1000
+	mmc1RAMBankSize = 0x2000
+	// SUROM and SXROM split PRG ROM into two 256KB blocks of 16 banks
+	mmc1OuterBanks = 16
+)
+
+func newMMC1(cart *Cartridge) Mapper {
+	m := &mmc1{
+		board:   newBoard(cart),
+		variant: mmc1VariantOf(cart),
+		control: mmc1PRGModeFixed,
+	}
+	m.updateMirroring()
+	return m
+}
+
+// mmc1VariantOf picks the board from the NES 2.0 submapper or, failing that, from the memory sizes
+func mmc1VariantOf(cart *Cartridge) mmc1Variant {
+	prgRAM := cart.PRGRAMSize + cart.PRGNVRAMSize
+	switch {
+	case cart.Format == NES20 && cart.Submapper == 5:
+		return serom
+	case cart.PRGROMSize > 0x40000 && prgRAM > 0x4000:
+		return sxrom
+	case cart.PRGROMSize > 0x40000:
+		return surom
+	case prgRAM > 0x2000:
+		return sorom
+	case cart.CHRROMSize == 0:
+		return snrom
+	}
+	return mmc1Standard
+}
+
+// Tick advances the cycle counter used to detect writes on consecutive cycles. It is called every CPU cycle
+func (m *mmc1) Tick() {
+	m.cycle++
+}
+
+func (m *mmc1) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= prgROMStart:
+		return m.prg[m.prgOffset(m.prgBankAt(address), mmc1PRGBankSize, address)], 0xFF
+	case address >= prgRAMStart:
+		if !m.prgRAMEnabled() || len(m.prgRAM) == 0 {
+			return 0, 0
+		}
+		return m.prgRAM[m.prgRAMOffset(address)], 0xFF
+	}
+	return 0, 0
+}
+
+func (m *mmc1) WriteCPU(address uint16, data uint8) {
+	switch {
+	case address >= prgROMStart:
+		m.writeRegister(address, data)
+	case address >= prgRAMStart:
+		if m.prgRAMEnabled() && len(m.prgRAM) != 0 {
+			m.prgRAM[m.prgRAMOffset(address)] = data
+		}
+	}
+}
+
+func (m *mmc1) ReadPPU(address uint16) uint8 {
+	if address <= patternTableEnd {
+		m.chrA12 = address&0x1000 != 0
+		return m.chr[m.chrOffset(m.chrBankAt(address), mmc1CHRBankSize, address)]
+	}
+	return m.readNametable(address)
+}
+
+func (m *mmc1) WritePPU(address uint16, data uint8) {
+	if address <= patternTableEnd {
+		m.chrA12 = address&0x1000 != 0
+		m.writeCHR(m.chrOffset(m.chrBankAt(address), mmc1CHRBankSize, address), data)
+		return
+	}
+	m.writeNametable(address, data)
+}
+
+// writeRegister shifts one bit into the shift register. The fifth write copies the shift register into the register
+// selected by bits 13 and 14 of the address
+func (m *mmc1) writeRegister(address uint16, data uint8) {
+	consecutive := m.cycle-m.lastWrite <= 1 && m.lastWrite != 0
+	m.lastWrite = m.cycle
+	if consecutive {
+		return
+	}
+
+	if data&mmc1ShiftReset != 0 {
+		m.shift = 0
+		m.shiftCount = 0
+		m.control |= mmc1PRGModeFixed
+		return
+	}
+
+	m.shift |= (data & 0x01) << m.shiftCount
+	m.shiftCount++
+	if m.shiftCount < 5 {
+		return
+	}
+
+	value := m.shift
+	m.shift = 0
+	m.shiftCount = 0
+	switch address & 0xE000 {
+	case 0x8000:
+		m.control = value
+		m.updateMirroring()
+	case 0xA000:
+		m.chrBank0 = value
+	case 0xC000:
+		m.chrBank1 = value
+	case 0xE000:
+		m.prgBank = value
+	}
+}
+
+// updateMirroring applies the mirroring bits of the control register
+func (m *mmc1) updateMirroring() {
+	switch m.control & 0x03 {
+	case 0:
+		m.mirroring = SingleScreenLower
+	case 1:
+		m.mirroring = SingleScreenUpper
+	case 2:
+		m.mirroring = Vertical
+	case 3:
+		m.mirroring = Horizontal
+	}
+}
+
+// prgBankAt returns the 16KB PRG bank mapped at address
+func (m *mmc1) prgBankAt(address uint16) int {
+	upper := address >= 0xC000
+	if m.variant == serom {
+		if upper {
+			return 1
+		}
+		return 0
+	}
+
+	bank := int(m.prgBank & 0x0F)
+	switch (m.control >> 2) & 0x03 {
+	case 0, 1:
+		// Switch 32KB at $8000, ignoring the low bit of the bank number
+		bank &^= 1
+		if upper {
+			bank |= 1
+		}
+	case 2:
+		// Fix the first bank at $8000 and switch 16KB at $C000
+		if !upper {
+			bank = 0
+		}
+	case 3:
+		// Fix the last bank at $C000 and switch 16KB at $8000
+		if upper {
+			bank = mmc1OuterBanks - 1
+		}
+	}
+
+	if (m.variant == surom || m.variant == sxrom) && m.activeCHRBank()&0x10 != 0 {
+		bank += mmc1OuterBanks
+	}
+	return bank
+}
+
+// chrBankAt returns the 4KB CHR bank mapped at address
+func (m *mmc1) chrBankAt(address uint16) int {
+	upper := address&0x1000 != 0
+	if m.control&mmc1CHR4KB != 0 {
+		if upper {
+			return int(m.chrBank1)
+		}
+		return int(m.chrBank0)
+	}
+	// 8KB mode ignores the low bit of CHR bank 0
+	bank := int(m.chrBank0 &^ 1)
+	if upper {
+		bank |= 1
+	}
+	return bank
+}
+
+// activeCHRBank returns the CHR bank register currently driving the CHR address lines. Boards that use the upper CHR
+// bits for PRG see whichever register was last selected by the PPU
+func (m *mmc1) activeCHRBank() uint8 {
+	if m.control&mmc1CHR4KB != 0 && m.chrA12 {
+		return m.chrBank1
+	}
+	return m.chrBank0
+}
+
+func (m *mmc1) prgRAMEnabled() bool {
+	if m.prgBank&mmc1PRGRAMOff != 0 {
+		return false
+	}
+	if m.variant == snrom && m.activeCHRBank()&0x10 != 0 {
+		return false
+	}
+	return true
+}
+
+// prgRAMOffset returns the offset into PRG RAM of address, taking the PRG RAM bank of SOROM and SXROM into account
+func (m *mmc1) prgRAMOffset(address uint16) int {
+	bank := 0
+	switch m.variant {
+	case sorom:
+		bank = int(m.activeCHRBank()>>3) & 0x01
+	case sxrom:
+		bank = int(m.activeCHRBank()>>2) & 0x03
+	}
+	return (bank*mmc1RAMBankSize + int(address-prgRAMStart)) % len(m.prgRAM)
+}
@@ -0,0 +1,261 @@
+package cartridge
+
+// mmc3 is mapper 4, used by the TxROM boards, and the closely related MMC6 on the HKROM board.
+// http://wiki.nesdev.com/w/index.php/MMC3 and http://wiki.nesdev.com/w/index.php/MMC6
+type mmc3 struct {
+	board
+	revision mmc3Revision
+
+	// Bank select. Bits 0-2 pick the bank register updated by the next bank data write
+	bankSelect uint8
+	registers  [8]uint8
+	prgRAMCtrl uint8
+
+	irqLatch   uint8
+	irqCounter uint8
+	irqReload  bool
+	irqEnabled bool
+
+	// The scanline counter is clocked by rising edges of PPU A12. Short pulses while sprites are fetched are filtered
+	// out by ignoring edges unless A12 has been low for a few CPU cycles
+	a12        bool
+	a12LowFrom uint64
+	cycle      uint64
+}
+
+type mmc3Revision uint8
+
+const (
+	// MMC3B and MMC3C trigger an IRQ whenever the counter is 0 after being clocked
+	mmc3New mmc3Revision = iota
+	// MMC3A (and some MMC3B) only trigger an IRQ when the counter reaches 0 by decrementing or being reloaded after a
+	// write to $C001. A latch of 0 therefore fires once rather than on every scanline
+	mmc3Old
+	// MMC6 uses the old IRQ behaviour and has 1KB of PRG RAM inside the mapper with separate protection for each half
+	mmc6
+)
+
+const (
+	mmc3PRGMode     uint8  = 0x40
+	mmc3CHRInvert   uint8  = 0x80
+	mmc6RAMEnable   uint8  = 0x20
+	mmc3RAMEnable   uint8  = 0x80
+	mmc3RAMProtect  uint8  = 0x40
+	mmc3PRGBankSize        = 0x2000
+	mmc3CHRBankSize        = 0x0400
+	mmc6RAMSize            = 0x0400
+	mmc6RAMStart    uint16 = 0x7000
+
+	// The number of CPU cycles A12 must stay low before a rising edge clocks the counter
+	mmc3A12Filter = 3
+)
+
+func newMMC3(cart *Cartridge) Mapper {
+	// Many games use the PRG RAM without ever enabling it through $A001, so it starts enabled and writable
+	m := &mmc3{
+		board:      newBoard(cart),
+		prgRAMCtrl: mmc3RAMEnable,
+	}
+	switch {
+	case cart.Format == NES20 && cart.Submapper == 1:
+		m.revision = mmc6
+		m.prgRAM = make([]uint8, mmc6RAMSize)
+		m.prgRAMCtrl = 0
+	case cart.Format == NES20 && cart.Submapper == 4:
+		m.revision = mmc3Old
+	}
+	return m
+}
+
+// Tick counts CPU cycles for the A12 filter. It is called every CPU cycle
+func (m *mmc3) Tick() {
+	m.cycle++
+}
+
+func (m *mmc3) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= prgROMStart:
+		return m.prg[m.prgOffset(m.prgBankAt(address), mmc3PRGBankSize, address)], 0xFF
+	case address >= prgRAMStart:
+		if m.revision == mmc6 {
+			return m.readMMC6RAM(address)
+		}
+		if m.prgRAMCtrl&mmc3RAMEnable == 0 {
+			return 0, 0
+		}
+		return m.readPRGRAM(address)
+	}
+	return 0, 0
+}
+
+func (m *mmc3) WriteCPU(address uint16, data uint8) {
+	switch {
+	case address >= prgROMStart:
+		m.writeRegister(address, data)
+	case address >= prgRAMStart:
+		if m.revision == mmc6 {
+			m.writeMMC6RAM(address, data)
+			return
+		}
+		if m.prgRAMCtrl&mmc3RAMEnable != 0 && m.prgRAMCtrl&mmc3RAMProtect == 0 {
+			m.writePRGRAM(address, data)
+		}
+	}
+}
+
+func (m *mmc3) ReadPPU(address uint16) uint8 {
+	m.ObserveAddress(address)
+	if address <= patternTableEnd {
+		return m.chr[m.chrOffset(m.chrBankAt(address), mmc3CHRBankSize, address)]
+	}
+	return m.readNametable(address)
+}
+
+func (m *mmc3) WritePPU(address uint16, data uint8) {
+	m.ObserveAddress(address)
+	if address <= patternTableEnd {
+		m.writeCHR(m.chrOffset(m.chrBankAt(address), mmc3CHRBankSize, address), data)
+		return
+	}
+	m.writeNametable(address, data)
+}
+
+// ObserveAddress watches the PPU address bus for rising edges of A12, which clock the scanline counter
+func (m *mmc3) ObserveAddress(address uint16) {
+	a12 := address&0x1000 != 0
+	switch {
+	case a12 && !m.a12:
+		if m.cycle-m.a12LowFrom >= mmc3A12Filter {
+			m.clockScanlineCounter()
+		}
+	case !a12 && m.a12:
+		m.a12LowFrom = m.cycle
+	}
+	m.a12 = a12
+}
+
+// clockScanlineCounter reloads the counter if it is 0 or a reload was requested, otherwise decrements it, then
+// triggers an IRQ depending on the revision
+func (m *mmc3) clockScanlineCounter() {
+	wasZero := m.irqCounter == 0
+	reloaded := m.irqReload
+	if wasZero || m.irqReload {
+		m.irqCounter = m.irqLatch
+		m.irqReload = false
+	} else {
+		m.irqCounter--
+	}
+
+	if m.irqCounter != 0 || !m.irqEnabled {
+		return
+	}
+	if m.revision == mmc3New || !wasZero || reloaded {
+		m.setIRQ(true)
+	}
+}
+
+// writeRegister handles writes to $8000-$FFFF. Each 8KB range holds two registers selected by the low address bit
+func (m *mmc3) writeRegister(address uint16, data uint8) {
+	even := address&0x01 == 0
+	switch address & 0xE000 {
+	case 0x8000:
+		if even {
+			m.bankSelect = data
+		} else {
+			m.registers[m.bankSelect&0x07] = data
+		}
+	case 0xA000:
+		if even {
+			if m.mirroring != FourScreen {
+				if data&0x01 == 0 {
+					m.mirroring = Vertical
+				} else {
+					m.mirroring = Horizontal
+				}
+			}
+		} else {
+			m.prgRAMCtrl = data
+		}
+	case 0xC000:
+		if even {
+			m.irqLatch = data
+		} else {
+			m.irqCounter = 0
+			m.irqReload = true
+		}
+	case 0xE000:
+		if even {
+			m.irqEnabled = false
+			m.setIRQ(false)
+		} else {
+			m.irqEnabled = true
+		}
+	}
+}
+
+// prgBankAt returns the 8KB PRG bank mapped at address. R6 and the second to last bank swap places depending on the
+// PRG mode. R7 and the last bank never move
+func (m *mmc3) prgBankAt(address uint16) int {
+	secondLast := -2
+	r6 := int(m.registers[6] & 0x3F)
+	switch address & 0xE000 {
+	case 0x8000:
+		if m.bankSelect&mmc3PRGMode != 0 {
+			return secondLast
+		}
+		return r6
+	case 0xA000:
+		return int(m.registers[7] & 0x3F)
+	case 0xC000:
+		if m.bankSelect&mmc3PRGMode != 0 {
+			return r6
+		}
+		return secondLast
+	}
+	return -1
+}
+
+// chrBankAt returns the 1KB CHR bank mapped at address. R0 and R1 select 2KB banks and R2-R5 select 1KB banks. CHR
+// inversion swaps the two pattern tables
+func (m *mmc3) chrBankAt(address uint16) int {
+	if m.bankSelect&mmc3CHRInvert != 0 {
+		address ^= 0x1000
+	}
+	slot := int(address>>10) & 0x07
+	if slot < 4 {
+		bank := int(m.registers[slot>>1] &^ 1)
+		return bank | slot&0x01
+	}
+	return int(m.registers[slot-2])
+}
+
+// mmc6RAMHalf returns which 512 byte half of the MMC6 RAM address falls in along with whether it can be read and
+// written
+func (m *mmc3) mmc6RAMHalf(address uint16) (readable, writable bool) {
+	shift := uint(4)
+	if address&0x0200 != 0 {
+		shift = 6
+	}
+	return m.prgRAMCtrl&(0x02<<shift) != 0, m.prgRAMCtrl&(0x01<<shift) != 0
+}
+
+// readMMC6RAM reads the 1KB of RAM mirrored across $7000-$7FFF. Reading a disabled half returns 0 while the other
+// half is enabled, and open bus when both are disabled
+func (m *mmc3) readMMC6RAM(address uint16) (uint8, uint8) {
+	if address < mmc6RAMStart || m.bankSelect&mmc6RAMEnable == 0 || m.prgRAMCtrl&0xA0 == 0 {
+		return 0, 0
+	}
+	if readable, _ := m.mmc6RAMHalf(address); !readable {
+		return 0, 0xFF
+	}
+	return m.prgRAM[int(address)%mmc6RAMSize], 0xFF
+}
+
+func (m *mmc3) writeMMC6RAM(address uint16, data uint8) {
+	if address < mmc6RAMStart || m.bankSelect&mmc6RAMEnable == 0 {
+		return
+	}
+	if readable, writable := m.mmc6RAMHalf(address); readable && writable {
+		m.prgRAM[int(address)%mmc6RAMSize] = data
+	}
+}
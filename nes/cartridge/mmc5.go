@@ -0,0 +1,496 @@
+package cartridge
+
+import "github.com/kevinwmiller/nesgo/nes/apu"
+
+// mmc5 is mapper 5, used by the ExROM boards. It is the most capable Nintendo mapper with four PRG banking modes, up to
+// 64KB of PRG RAM, separate CHR banks for backgrounds and 8x16 sprites, 1KB of extra RAM that can act as a nametable
+// or per-tile attributes, a vertical split screen, a scanline IRQ and an 8x8 multiplier.
+// http://wiki.nesdev.com/w/index.php/MMC5
+type mmc5 struct {
+	board
+
+	prgMode    uint8
+	chrMode    uint8
+	prgProtect [2]uint8
+	// $5113-$5117. Bit 7 of $5114-$5116 selects ROM rather than RAM
+	prgBanks [5]uint8
+
+	// Sprite banks $5120-$5127 and background banks $5128-$512B, with the upper bits from $5130 already applied
+	chrA     [8]int
+	chrB     [4]int
+	chrUpper uint8
+	// The last CHR register written was one of $5128-$512B. Used for accesses outside of rendering
+	lastCHRB bool
+
+	exRAM     [0x0400]uint8
+	exRAMMode uint8
+	// Two bits for each logical nametable choosing CIRAM page 0 or 1, ExRAM or fill mode
+	nametableMapping uint8
+	fillTile         uint8
+	fillAttribute    uint8
+
+	splitControl uint8
+	splitScroll  uint8
+	splitBank    uint8
+
+	irqCompare uint8
+	irqEnabled bool
+	irqPending bool
+
+	multiplicand uint8
+	multiplier   uint8
+
+	// State snooped from PPUCTRL
+	sprites8x16 bool
+
+	// Scanline detection. The PPU reads the same nametable address three times in a row at the end of each rendered
+	// scanline. When that happens the MMC5 knows rendering is in progress and counts fetches from there to tell
+	// background and sprite fetches apart. When the PPU stops fetching for a few CPU cycles rendering has stopped
+	inFrame       bool
+	scanline      uint8
+	lastFetch     uint16
+	matchingFetch int
+	fetch         int
+	idleCycles    int
+
+	// The ExRAM byte fetched with the current background tile in extended attribute mode
+	exAttribute uint8
+	// The current background tile comes from the split region along with the row of the split being drawn
+	inSplit  bool
+	splitRow int
+
+	sound mmc5Audio
+}
+
+const (
+	mmc5PRGRAMSize    = 0x10000
+	mmc5PRGBankSize   = 0x2000
+	mmc5ROMSelect     = 0x80
+	mmc5ExRAMStart    = 0x5C00
+	mmc5ExRAMEnd      = 0x5FFF
+	mmc5ExtAttributes = 1
+	mmc5ExRAMWritable = 2
+	mmc5ExRAMReadOnly = 3
+
+	// Nametable sources selected by $5105
+	mmc5CIRAM0 = 0
+	mmc5CIRAM1 = 1
+	mmc5ExRAM  = 2
+	mmc5Fill   = 3
+
+	mmc5SplitEnable = 0x80
+	mmc5SplitRight  = 0x40
+
+	// Fetches counted from the start of a scanline. 32 background tiles are fetched first, four reads each, then 8
+	// sprites with four reads each, then the first two tiles of the next scanline
+	mmc5SpriteFetches   = 128
+	mmc5PrefetchFetches = 160
+	mmc5DummyFetches    = 168
+	// The number of CPU cycles without a PPU read before the MMC5 decides rendering has stopped
+	mmc5IdleCycles = 3
+
+	mmc5NMIVectorLow  uint16 = 0xFFFA
+	mmc5NMIVectorHigh uint16 = 0xFFFB
+
+	attributeTableOffset = 0x03C0
+)
+
+func newMMC5(cart *Cartridge) Mapper {
+	m := &mmc5{
+		board:        newBoard(cart),
+		prgMode:      3,
+		chrMode:      3,
+		multiplicand: 0xFF,
+		multiplier:   0xFF,
+	}
+	m.prgBanks[4] = 0xFF
+	// Most iNES dumps don't describe the PRG RAM. Give them the most an ExROM board can have
+	if cart.Format != NES20 && len(m.prgRAM) <= mmc5PRGBankSize {
+		m.prgRAM = make([]uint8, mmc5PRGRAMSize)
+	}
+	return m
+}
+
+// Tick clocks the sound channels and watches for the PPU to stop fetching, which means rendering has stopped
+func (m *mmc5) Tick() {
+	m.idleCycles++
+	if m.idleCycles >= mmc5IdleCycles {
+		m.inFrame = false
+	}
+	m.sound.tick()
+}
+
+func (m *mmc5) audio() apu.Expansion {
+	return &m.sound
+}
+
+// ObserveWrite follows writes to PPUCTRL for the sprite size and PPUMASK for rendering being disabled
+func (m *mmc5) ObserveWrite(address uint16, data uint8) {
+	switch address & 0x2007 {
+	case 0x2000:
+		m.sprites8x16 = data&0x20 != 0
+	case 0x2001:
+		if data&0x18 == 0 {
+			m.inFrame = false
+		}
+	}
+}
+
+func (m *mmc5) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= prgROMStart:
+		if address == mmc5NMIVectorLow || address == mmc5NMIVectorHigh {
+			m.inFrame = false
+		}
+		data, driven := m.readPRG(address)
+		m.sound.observeRead(address, data)
+		m.updateIRQ()
+		return data, driven
+	case address >= prgRAMStart:
+		return m.readPRG(address)
+	case address >= mmc5ExRAMStart && address <= mmc5ExRAMEnd:
+		if m.exRAMMode < mmc5ExRAMWritable {
+			return 0, 0
+		}
+		return m.exRAM[address-mmc5ExRAMStart], 0xFF
+	case address == 0x5204:
+		var status uint8
+		if m.irqPending {
+			status |= 0x80
+		}
+		if m.inFrame {
+			status |= 0x40
+		}
+		m.irqPending = false
+		m.updateIRQ()
+		return status, 0xFF
+	case address == 0x5010 || address == 0x5015:
+		data, _ := m.sound.read(address)
+		m.updateIRQ()
+		return data, 0xFF
+	case address == 0x5205:
+		return uint8(uint16(m.multiplicand) * uint16(m.multiplier)), 0xFF
+	case address == 0x5206:
+		return uint8(uint16(m.multiplicand) * uint16(m.multiplier) >> 8), 0xFF
+	}
+	return 0, 0
+}
+
+// PeekCPU reads without acknowledging IRQs or feeding the PCM channel
+func (m *mmc5) PeekCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= prgRAMStart:
+		return m.readPRG(address)
+	case address == 0x5204:
+		var status uint8
+		if m.irqPending {
+			status |= 0x80
+		}
+		if m.inFrame {
+			status |= 0x40
+		}
+		return status, 0xFF
+	case address == 0x5010 || address == 0x5015:
+		data, _ := m.sound.peek(address)
+		return data, 0xFF
+	}
+	return m.ReadCPU(address)
+}
+
+func (m *mmc5) WriteCPU(address uint16, data uint8) {
+	switch {
+	case address >= prgRAMStart:
+		m.writePRG(address, data)
+	case address >= mmc5ExRAMStart && address <= mmc5ExRAMEnd:
+		m.writeExRAM(address-mmc5ExRAMStart, data)
+	case address >= 0x5000 && address <= 0x5015:
+		m.sound.write(address, data)
+		m.updateIRQ()
+	case address >= 0x5113 && address <= 0x5117:
+		m.prgBanks[address-0x5113] = data
+	case address >= 0x5120 && address <= 0x5127:
+		m.chrA[address-0x5120] = int(m.chrUpper)<<8 | int(data)
+		m.lastCHRB = false
+	case address >= 0x5128 && address <= 0x512B:
+		m.chrB[address-0x5128] = int(m.chrUpper)<<8 | int(data)
+		m.lastCHRB = true
+	}
+
+	switch address {
+	case 0x5100:
+		m.prgMode = data & 0x03
+	case 0x5101:
+		m.chrMode = data & 0x03
+	case 0x5102, 0x5103:
+		m.prgProtect[address-0x5102] = data & 0x03
+	case 0x5104:
+		m.exRAMMode = data & 0x03
+	case 0x5105:
+		m.nametableMapping = data
+	case 0x5106:
+		m.fillTile = data
+	case 0x5107:
+		m.fillAttribute = data & 0x03
+	case 0x5130:
+		m.chrUpper = data & 0x03
+	case 0x5200:
+		m.splitControl = data
+	case 0x5201:
+		m.splitScroll = data
+	case 0x5202:
+		m.splitBank = data
+	case 0x5203:
+		m.irqCompare = data
+	case 0x5204:
+		m.irqEnabled = data&0x80 != 0
+		m.updateIRQ()
+	case 0x5205:
+		m.multiplicand = data
+	case 0x5206:
+		m.multiplier = data
+	}
+}
+
+func (m *mmc5) ReadPPU(address uint16) uint8 {
+	m.watchFetch(address)
+	if address <= patternTableEnd {
+		return m.chr[m.chrOffsetAt(address)]
+	}
+	return m.readNametableMMC5(address)
+}
+
+func (m *mmc5) WritePPU(address uint16, data uint8) {
+	if address <= patternTableEnd {
+		m.writeCHR(m.chrOffsetAt(address), data)
+		return
+	}
+	offset := int(address) & 0x03FF
+	switch m.nametableSource(address) {
+	case mmc5CIRAM0, mmc5CIRAM1:
+		m.vram[int(m.nametableSource(address))<<10|offset] = data
+	case mmc5ExRAM:
+		if m.exRAMMode < mmc5ExRAMWritable {
+			m.exRAM[offset] = data
+		}
+	}
+}
+
+// prgBank returns the 8KB bank mapped at a CPU address and whether it is in ROM
+func (m *mmc5) prgBank(address uint16) (int, bool) {
+	if address < prgROMStart {
+		return int(m.prgBanks[0] & 0x07), false
+	}
+	window := int(address>>13) & 0x03
+	// index is the register in prgBanks and size is the bank size in 8KB units
+	var index, size int
+	switch m.prgMode {
+	case 0:
+		index, size = 4, 4
+	case 1:
+		index, size = 2+window&0x02, 2
+	case 2:
+		if window < 2 {
+			index, size = 2, 2
+		} else {
+			index, size = 1+window, 1
+		}
+	default:
+		index, size = 1+window, 1
+	}
+	// $5117 always maps ROM. The bits below the bank size come from the address
+	register := m.prgBanks[index]
+	rom := register&mmc5ROMSelect != 0 || index == 4
+	bank := int(register&0x7F)&^(size-1) | window&(size-1)
+	return bank, rom
+}
+
+func (m *mmc5) readPRG(address uint16) (uint8, uint8) {
+	bank, rom := m.prgBank(address)
+	if rom {
+		return m.prg[m.prgOffset(bank, mmc5PRGBankSize, address)], 0xFF
+	}
+	if len(m.prgRAM) == 0 {
+		return 0, 0
+	}
+	return m.prgRAM[bankOffset(len(m.prgRAM), bank&0x07, mmc5PRGBankSize, address)], 0xFF
+}
+
+func (m *mmc5) writePRG(address uint16, data uint8) {
+	bank, rom := m.prgBank(address)
+	if rom || len(m.prgRAM) == 0 || m.prgProtect[0] != 0x02 || m.prgProtect[1] != 0x01 {
+		return
+	}
+	m.prgRAM[bankOffset(len(m.prgRAM), bank&0x07, mmc5PRGBankSize, address)] = data
+}
+
+// writeExRAM handles CPU writes to ExRAM. While it is used by the PPU it can only be written during rendering, and
+// writes at any other time store 0
+func (m *mmc5) writeExRAM(offset uint16, data uint8) {
+	switch m.exRAMMode {
+	case mmc5ExRAMReadOnly:
+	case mmc5ExRAMWritable:
+		m.exRAM[offset] = data
+	default:
+		if !m.inFrame {
+			data = 0
+		}
+		m.exRAM[offset] = data
+	}
+}
+
+// updateIRQ drives the IRQ line from the scanline IRQ and the PCM channel's IRQ
+func (m *mmc5) updateIRQ() {
+	m.setIRQ(m.irqPending && m.irqEnabled || m.sound.irq())
+}
+
+// watchFetch follows PPU reads to detect scanlines and count fetches within them
+func (m *mmc5) watchFetch(address uint16) {
+	m.idleCycles = 0
+	m.fetch++
+	if address >= nametableStart && address == m.lastFetch {
+		m.matchingFetch++
+		if m.matchingFetch == 2 {
+			m.detectScanline()
+		}
+	} else {
+		m.matchingFetch = 0
+	}
+	m.lastFetch = address
+}
+
+// detectScanline is called at the start of each rendered scanline. The first one starts a new frame
+func (m *mmc5) detectScanline() {
+	m.fetch = 0
+	if !m.inFrame {
+		m.inFrame = true
+		m.scanline = 0
+		m.irqPending = false
+		m.updateIRQ()
+		return
+	}
+	m.scanline++
+	if m.scanline == m.irqCompare {
+		m.irqPending = true
+		m.updateIRQ()
+	}
+}
+
+// spriteFetch returns true while the PPU is fetching sprite patterns
+func (m *mmc5) spriteFetch() bool {
+	return m.inFrame && m.fetch >= mmc5SpriteFetches && m.fetch < mmc5PrefetchFetches
+}
+
+// backgroundFetch returns true while the PPU is fetching background tiles along with the column and scanline of the
+// tile being fetched
+func (m *mmc5) backgroundFetch() (column, scanline int, ok bool) {
+	switch {
+	case !m.inFrame:
+		return 0, 0, false
+	case m.fetch < mmc5SpriteFetches:
+		return m.fetch/4 + 2, int(m.scanline), true
+	case m.fetch >= mmc5PrefetchFetches && m.fetch < mmc5DummyFetches:
+		return (m.fetch - mmc5PrefetchFetches) / 4, int(m.scanline) + 1, true
+	}
+	return 0, 0, false
+}
+
+// chrOffsetAt returns the offset into CHR memory for a pattern table address. 8x16 sprites use $5120-$5127 and the
+// background uses $5128-$512B. Outside of rendering the last set written is used
+func (m *mmc5) chrOffsetAt(address uint16) int {
+	if _, _, ok := m.backgroundFetch(); ok {
+		if m.inSplit {
+			offset := int(address)&0x0FF8 | m.splitRow&0x07
+			return m.chrOffset(int(m.splitBank), 0x1000, uint16(offset))
+		}
+		if m.exRAMMode == mmc5ExtAttributes {
+			bank := int(m.chrUpper)<<6 | int(m.exAttribute&0x3F)
+			return m.chrOffset(bank, 0x1000, address)
+		}
+	}
+
+	size := 0x2000 >> m.chrMode
+	useA := !m.sprites8x16 || m.spriteFetch() || (!m.inFrame && !m.lastCHRB)
+	if useA {
+		slot := int(address) / size
+		return m.chrOffset(m.chrA[(slot+1)*(8>>m.chrMode)-1], size, address)
+	}
+	if m.chrMode == 0 {
+		return m.chrOffset(m.chrB[3], size, address)
+	}
+	slot := int(address&0x0FFF) / size
+	return m.chrOffset(m.chrB[(slot+1)*(8>>m.chrMode)-1], size, address)
+}
+
+// nametableSource returns where a nametable address is mapped by $5105
+func (m *mmc5) nametableSource(address uint16) uint8 {
+	table := (address >> 10) & 0x03
+	return (m.nametableMapping >> (table * 2)) & 0x03
+}
+
+// readNametableMMC5 reads from the nametables, substituting the split screen and extended attributes while rendering
+func (m *mmc5) readNametableMMC5(address uint16) uint8 {
+	offset := int(address) & 0x03FF
+	attribute := offset >= attributeTableOffset
+
+	if column, scanline, ok := m.backgroundFetch(); ok {
+		if !attribute {
+			m.inSplit = m.splitActive(column)
+		}
+		if m.inSplit {
+			return m.readSplit(column, scanline, attribute)
+		}
+		if m.exRAMMode == mmc5ExtAttributes {
+			if !attribute {
+				m.exAttribute = m.exRAM[offset]
+			} else {
+				return replicateAttribute(m.exAttribute >> 6)
+			}
+		}
+	} else {
+		m.inSplit = false
+	}
+
+	switch source := m.nametableSource(address); source {
+	case mmc5CIRAM0, mmc5CIRAM1:
+		return m.vram[int(source)<<10|offset]
+	case mmc5ExRAM:
+		if m.exRAMMode < mmc5ExRAMWritable {
+			return m.exRAM[offset]
+		}
+		return 0
+	}
+	if attribute {
+		return replicateAttribute(m.fillAttribute)
+	}
+	return m.fillTile
+}
+
+// splitActive returns true if a tile column falls in the split region
+func (m *mmc5) splitActive(column int) bool {
+	if m.splitControl&mmc5SplitEnable == 0 || m.exRAMMode >= mmc5ExRAMWritable {
+		return false
+	}
+	threshold := int(m.splitControl & 0x1F)
+	if m.splitControl&mmc5SplitRight != 0 {
+		return column >= threshold
+	}
+	return column < threshold
+}
+
+// readSplit reads the nametable or attribute byte for the split region from ExRAM. The split has its own vertical
+// scroll, wrapping at the 30 rows of a nametable
+func (m *mmc5) readSplit(column, scanline int, attribute bool) uint8 {
+	m.splitRow = (scanline + int(m.splitScroll)) % 240
+	column &= 0x1F
+	if !attribute {
+		return m.exRAM[(m.splitRow>>3)<<5|column]
+	}
+	data := m.exRAM[attributeTableOffset|(m.splitRow>>5)<<3|column>>2]
+	shift := uint((m.splitRow>>2)&0x04 | column&0x02)
+	return replicateAttribute(data >> shift)
+}
+
+// replicateAttribute copies a 2 bit palette into all four quadrants of an attribute byte
+func replicateAttribute(palette uint8) uint8 {
+	return (palette & 0x03) * 0x55
+}
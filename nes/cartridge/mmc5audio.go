@@ -0,0 +1,123 @@
+package cartridge
+
+import "github.com/kevinwmiller/nesgo/nes/apu"
+
+// mmc5Audio is the MMC5's sound hardware: two pulse channels like the APU's but without sweep units, and an 8 bit PCM
+// channel that can be written directly or fed by reads from $8000-$BFFF.
+// http://wiki.nesdev.com/w/index.php/MMC5_audio
+type mmc5Audio struct {
+	pulse [2]apu.Pulse
+
+	// The envelopes and length counters are clocked at a fixed 240Hz instead of by a frame sequencer
+	frameTimer int
+	cycle      uint64
+
+	pcm        uint8
+	pcmRead    bool
+	pcmIRQ     bool
+	pcmPending bool
+}
+
+const (
+	mmc5FramePeriod = 7457
+	// The PCM channel at full scale is about as loud as a pulse at full volume
+	mmc5Volume = apuPulseLevel * 3
+
+	mmc5PCMReadMode  = 0x01
+	mmc5PCMIRQEnable = 0x80
+	mmc5PCMReadStart = 0x8000
+	mmc5PCMReadEnd   = 0xBFFF
+)
+
+// write handles writes to $5000-$5015
+func (a *mmc5Audio) write(address uint16, data uint8) {
+	switch {
+	case address <= 0x5007:
+		a.pulse[(address-0x5000)>>2].Write(address&0x03, data)
+	case address == 0x5010:
+		a.pcmRead = data&mmc5PCMReadMode != 0
+		a.pcmIRQ = data&mmc5PCMIRQEnable != 0
+	case address == 0x5011:
+		// 0 can't be written and leaves the level alone
+		if !a.pcmRead && data != 0 {
+			a.pcm = data
+		}
+	case address == 0x5015:
+		for i := range a.pulse {
+			a.pulse[i].SetEnabled(data&(1<<uint(i)) != 0)
+		}
+	}
+}
+
+// read handles reads of $5010 and $5015. Reading $5010 acknowledges the PCM IRQ
+func (a *mmc5Audio) read(address uint16) (uint8, bool) {
+	data, ok := a.peek(address)
+	if address == 0x5010 {
+		a.pcmPending = false
+	}
+	return data, ok
+}
+
+// peek returns what a read of $5010 or $5015 would without acknowledging the PCM IRQ
+func (a *mmc5Audio) peek(address uint16) (uint8, bool) {
+	var status uint8
+	switch address {
+	case 0x5010:
+		if a.pcmPending && a.pcmIRQ {
+			status |= 0x80
+		}
+		return status, true
+	case 0x5015:
+		for i := range a.pulse {
+			if a.pulse[i].Active() {
+				status |= 1 << uint(i)
+			}
+		}
+		return status, true
+	}
+	return 0, false
+}
+
+// observeRead feeds CPU reads of $8000-$BFFF to the PCM channel in read mode. Reading a 0 raises the PCM IRQ
+func (a *mmc5Audio) observeRead(address uint16, data uint8) {
+	if !a.pcmRead || address < mmc5PCMReadStart || address > mmc5PCMReadEnd {
+		return
+	}
+	if data == 0 {
+		a.pcmPending = true
+		return
+	}
+	a.pcm = data
+}
+
+// irq returns true while the PCM IRQ is asserted
+func (a *mmc5Audio) irq() bool {
+	return a.pcmPending && a.pcmIRQ
+}
+
+// tick is called every CPU cycle
+func (a *mmc5Audio) tick() {
+	a.cycle++
+	if a.cycle%2 == 0 {
+		for i := range a.pulse {
+			a.pulse[i].ClockTimer()
+		}
+	}
+	a.frameTimer++
+	if a.frameTimer == mmc5FramePeriod {
+		a.frameTimer = 0
+		for i := range a.pulse {
+			a.pulse[i].ClockEnvelope()
+			a.pulse[i].ClockLength()
+		}
+	}
+}
+
+func (a *mmc5Audio) Sample() float32 {
+	pulses := float32(a.pulse[0].Output()+a.pulse[1].Output()) / 15
+	return (pulses + float32(a.pcm)/0xFF) / 3
+}
+
+func (a *mmc5Audio) Volume() float32 {
+	return mmc5Volume
+}
@@ -0,0 +1,154 @@
+package cartridge
+
+import "github.com/kevinwmiller/nesgo/nes/apu"
+
+// n163 is the Namco 163, mapper 19. Three switchable 8KB PRG banks, eight 1KB CHR banks and four nametable banks that
+// can each point at CHR ROM or the console's VRAM, a 15 bit IRQ counter and up to 8 wavetable sound channels.
+// http://wiki.nesdev.com/w/index.php/Namco_163
+type n163 struct {
+	board
+
+	prgBanks [3]int
+	// Banks 0-7 are the pattern tables and 8-11 the nametables
+	chrBanks [12]int
+	// Bits 6 and 7 of $E800. When set, banks $E0-$FF in the lower or upper pattern table select CHR ROM instead of VRAM
+	chrRAMDisable uint8
+	// $F800 also write protects PRG RAM
+	protect uint8
+
+	irqCounter uint16
+	irqEnabled bool
+
+	sound         n163Audio
+	soundDisabled bool
+}
+
+const (
+	n163CIRAMBanks        = 0xE0
+	n163IRQCounterMax     = 0x7FFF
+	n163SoundDisable      = 0x40
+	n163PRGRAMWriteEnable = 0x40
+)
+
+func newN163(cart *Cartridge) Mapper {
+	return &n163{board: newBoard(cart)}
+}
+
+// Tick clocks the IRQ counter and the sound channels
+func (m *n163) Tick() {
+	if m.irqEnabled && m.irqCounter < n163IRQCounterMax {
+		m.irqCounter++
+		if m.irqCounter == n163IRQCounterMax {
+			m.setIRQ(true)
+		}
+	}
+	if !m.soundDisabled {
+		m.sound.tick()
+	}
+}
+
+func (m *n163) audio() apu.Expansion {
+	return &m.sound
+}
+
+func (m *n163) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= 0xE000:
+		return m.prg[m.prgOffset(-1, 0x2000, address)], 0xFF
+	case address >= prgROMStart:
+		return m.prg[m.prgOffset(m.prgBanks[(address-prgROMStart)>>13], 0x2000, address)], 0xFF
+	case address >= prgRAMStart:
+		return m.readPRGRAM(address)
+	case address >= 0x5800:
+		enabled := uint8(0)
+		if m.irqEnabled {
+			enabled = 0x80
+		}
+		return enabled | uint8(m.irqCounter>>8), 0xFF
+	case address >= 0x5000:
+		return uint8(m.irqCounter), 0xFF
+	case address >= 0x4800:
+		return m.sound.readData(), 0xFF
+	}
+	return 0, 0
+}
+
+// PeekCPU reads without auto-incrementing the sound RAM address
+func (m *n163) PeekCPU(address uint16) (uint8, uint8) {
+	if address >= 0x4800 && address < 0x5000 {
+		return m.sound.peekData(), 0xFF
+	}
+	return m.ReadCPU(address)
+}
+
+func (m *n163) WriteCPU(address uint16, data uint8) {
+	switch {
+	case address >= 0xF800:
+		m.protect = data
+		m.sound.writeAddress(data)
+	case address >= 0xF000:
+		m.prgBanks[2] = int(data & 0x3F)
+	case address >= 0xE800:
+		m.prgBanks[1] = int(data & 0x3F)
+		m.chrRAMDisable = data & 0xC0
+	case address >= 0xE000:
+		m.prgBanks[0] = int(data & 0x3F)
+		m.soundDisabled = data&n163SoundDisable != 0
+	case address >= prgROMStart:
+		m.chrBanks[(address-prgROMStart)>>11] = int(data)
+	case address >= prgRAMStart:
+		if m.prgRAMWritable(address) {
+			m.writePRGRAM(address, data)
+		}
+	case address >= 0x5800:
+		m.irqCounter = m.irqCounter&0x00FF | uint16(data&0x7F)<<8
+		m.irqEnabled = data&0x80 != 0
+		m.setIRQ(false)
+	case address >= 0x5000:
+		m.irqCounter = m.irqCounter&0x7F00 | uint16(data)
+		m.setIRQ(false)
+	case address >= 0x4800:
+		m.sound.writeData(data)
+	}
+}
+
+// prgRAMWritable returns true if $F800 allows writes to the 2KB of PRG RAM containing address. The upper nibble must
+// be 4 and the bit for the 2KB window clear
+func (m *n163) prgRAMWritable(address uint16) bool {
+	window := uint((address - prgRAMStart) >> 11)
+	return m.protect&0xF0 == n163PRGRAMWriteEnable && m.protect>>window&0x01 == 0
+}
+
+func (m *n163) ReadPPU(address uint16) uint8 {
+	ciram, offset := m.chrSource(address)
+	if ciram {
+		return m.vram[offset]
+	}
+	return m.chr[offset]
+}
+
+func (m *n163) WritePPU(address uint16, data uint8) {
+	if ciram, offset := m.chrSource(address); ciram {
+		m.vram[offset] = data
+	} else {
+		m.writeCHR(offset, data)
+	}
+}
+
+// chrSource returns where a PPU address is mapped: an offset into VRAM if ciram is true, otherwise into CHR memory.
+// Bank values $E0-$FF select a page of VRAM by their lowest bit. Nametables always can, the pattern tables only when
+// VRAM has not been disabled for that half
+func (m *n163) chrSource(address uint16) (ciram bool, offset int) {
+	slot := int(address>>10) & 0x0F
+	if slot >= 12 {
+		slot -= 4
+	}
+	bank := m.chrBanks[slot]
+	if bank >= n163CIRAMBanks {
+		ciram = slot >= 8 || m.chrRAMDisable&(0x40<<uint(slot>>2)) == 0
+	}
+	if ciram {
+		return true, (bank&0x01)<<10 | int(address)&0x03FF
+	}
+	return false, m.chrOffset(bank, 0x0400, address)
+}
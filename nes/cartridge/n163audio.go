@@ -0,0 +1,102 @@
+package cartridge
+
+// n163Audio is the Namco 163's wavetable synthesiser. Up to 8 channels play 4 bit samples from 128 bytes of internal
+// RAM that also holds the channel registers. The chip only has one DAC and serves the channels one at a time, 15 CPU
+// cycles each, so with many channels enabled their outputs are multiplexed at an audible rate.
+// http://wiki.nesdev.com/w/index.php/Namco_163_audio
+type n163Audio struct {
+	ram [n163RAMSize]uint8
+	// The RAM address port. Bit 7 increments the address after each data access
+	address uint8
+
+	// The channel being updated and how many cycles it has left
+	channel int
+	cycles  int
+	output  float32
+}
+
+const (
+	n163RAMSize        = 0x80
+	n163ChannelCycles  = 15
+	n163AutoIncrement  = 0x80
+	n163ChannelsStart  = 0x40
+	n163ChannelSize    = 8
+	n163ChannelsOffset = 0x7F
+	// Each channel's output is (sample - 8) * volume
+	n163MaxOutput = 8 * 15
+	// With one channel enabled, a full volume wave is about three times as loud as an APU pulse at full volume
+	n163Volume = apuPulseLevel * 1.5
+)
+
+// writeAddress writes the address port
+func (a *n163Audio) writeAddress(data uint8) {
+	a.address = data
+}
+
+// writeData writes the RAM byte at the address port
+func (a *n163Audio) writeData(data uint8) {
+	a.ram[a.address&0x7F] = data
+	a.increment()
+}
+
+// readData reads the RAM byte at the address port
+func (a *n163Audio) readData() uint8 {
+	data := a.peekData()
+	a.increment()
+	return data
+}
+
+// peekData returns the byte at the sound RAM address without auto-incrementing it
+func (a *n163Audio) peekData() uint8 {
+	return a.ram[a.address&0x7F]
+}
+
+func (a *n163Audio) increment() {
+	if a.address&n163AutoIncrement != 0 {
+		a.address = n163AutoIncrement | (a.address+1)&0x7F
+	}
+}
+
+// channels returns the number of enabled channels, 1-8. The highest channels are the ones enabled
+func (a *n163Audio) channels() int {
+	return int(a.ram[n163ChannelsOffset]>>4&0x07) + 1
+}
+
+// tick is called every CPU cycle
+func (a *n163Audio) tick() {
+	if a.cycles > 0 {
+		a.cycles--
+		return
+	}
+	a.cycles = n163ChannelCycles - 1
+	if a.channel < 8-a.channels() {
+		a.channel = 7
+	}
+	a.output = a.update(a.channel)
+	a.channel--
+}
+
+// update advances a channel's phase and returns its output
+func (a *n163Audio) update(channel int) float32 {
+	r := a.ram[n163ChannelsStart+channel*n163ChannelSize:]
+	frequency := uint32(r[4]&0x03)<<16 | uint32(r[2])<<8 | uint32(r[0])
+	phase := uint32(r[5])<<16 | uint32(r[3])<<8 | uint32(r[1])
+	length := (256 - uint32(r[4]&0xFC)) << 16
+	phase = (phase + frequency) % length
+	r[5], r[3], r[1] = uint8(phase>>16), uint8(phase>>8), uint8(phase)
+
+	position := (uint32(r[6]) + phase>>16) & 0xFF
+	sample := a.ram[position>>1&0x7F]
+	if position&0x01 != 0 {
+		sample >>= 4
+	}
+	return float32((int(sample&0x0F)-8)*int(r[7]&0x0F)) / n163MaxOutput
+}
+
+func (a *n163Audio) Sample() float32 {
+	return a.output
+}
+
+func (a *n163Audio) Volume() float32 {
+	return n163Volume
+}
@@ -0,0 +1,45 @@
+package cartridge
+
+// nrom is mapper 0. The PRG ROM is 16KB (NROM-128), mirrored across $8000-$FFFF, or 32KB (NROM-256). CHR is a single
+// 8KB bank and mirroring is fixed by solder pads. Family BASIC adds PRG RAM at $6000-$7FFF.
+// http://wiki.nesdev.com/w/index.php/NROM
+type nrom struct {
+	board
+}
+
+func newNROM(cart *Cartridge) Mapper {
+	return &nrom{
+		board: newBoard(cart),
+	}
+}
+
+func (m *nrom) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= prgROMStart:
+		return m.prg[int(address-prgROMStart)%len(m.prg)], 0xFF
+	case address >= prgRAMStart:
+		return m.readPRGRAM(address)
+	}
+	return 0, 0
+}
+
+func (m *nrom) WriteCPU(address uint16, data uint8) {
+	if address >= prgRAMStart && address <= prgRAMEnd {
+		m.writePRGRAM(address, data)
+	}
+}
+
+func (m *nrom) ReadPPU(address uint16) uint8 {
+	if address <= patternTableEnd {
+		return m.chr[int(address)%len(m.chr)]
+	}
+	return m.readNametable(address)
+}
+
+func (m *nrom) WritePPU(address uint16, data uint8) {
+	if address <= patternTableEnd {
+		m.writeCHR(int(address)%len(m.chr), data)
+		return
+	}
+	m.writeNametable(address, data)
+}
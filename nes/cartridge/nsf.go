@@ -0,0 +1,309 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// NSF and NSFe file layouts. http://wiki.nesdev.com/w/index.php/NSF and http://wiki.nesdev.com/w/index.php/NSFe
+const (
+	nsfHeaderSize = 0x80
+	nsfStringSize = 32
+	// Play rates used when an NSFe has no RATE chunk, in microseconds
+	nsfDefaultNTSCSpeed = 16639
+	nsfDefaultPALSpeed  = 19997
+)
+
+var (
+	nsfMagic  = []uint8{'N', 'E', 'S', 'M', 0x1A}
+	nsfeMagic = []uint8{'N', 'S', 'F', 'E'}
+)
+
+// ErrNotNSF is returned when a file is neither an NSF nor an NSFe file
+var ErrNotNSF = errors.New("not an NSF file")
+
+// ExpansionChips is a set of sound chips an NSF tune uses on top of the APU
+type ExpansionChips uint8
+
+// Expansion sound chips
+const (
+	ChipVRC6 ExpansionChips = 1 << iota
+	ChipVRC7
+	ChipFDS
+	ChipMMC5
+	ChipN163
+	ChipSunsoft5B
+)
+
+// NSF is a tune ripped from a game: the sound driver and music data along with the addresses of its entry points.
+// Playing a song calls the INIT routine once with the song number and then the PLAY routine at a fixed rate
+type NSF struct {
+	Songs int
+	// The song to play first, counting from 0
+	StartSong int
+
+	LoadAddress uint16
+	InitAddress uint16
+	PlayAddress uint16
+	// The rate at which PLAY is called on each region, in microseconds
+	NTSCSpeed uint16
+	PALSpeed  uint16
+	Timing    Timing
+	Chips     ExpansionChips
+
+	// The initial 4KB banks at $8000-$FFFF. If none are set the tune is not bank switched and is loaded at LoadAddress
+	Banks [8]uint8
+	Data  []uint8
+
+	Title     string
+	Artist    string
+	Copyright string
+	Ripper    string
+
+	// Per track details. NSF files only have the number of songs, so their tracks are left untitled and untimed
+	Tracks []Track
+	// The order to play the tracks in, as track numbers counting from 0. Every track in order if the file has none
+	Playlist []int
+}
+
+// Track describes one song of an NSF
+type Track struct {
+	Title string
+	// How long the track plays before fading out and how long the fade lasts. Negative if the file doesn't say
+	Length time.Duration
+	Fade   time.Duration
+}
+
+// Banked returns true if the tune uses bank switching
+func (n *NSF) Banked() bool {
+	return n.Banks != [8]uint8{}
+}
+
+// LoadNSF reads and parses an NSF or NSFe file
+func LoadNSF(path string) (*NSF, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	n, err := ParseNSF(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return n, nil
+}
+
+// ParseNSF parses the contents of an NSF or NSFe file
+func ParseNSF(data []uint8) (*NSF, error) {
+	var n *NSF
+	var err error
+	switch {
+	case bytes.HasPrefix(data, nsfMagic):
+		n, err = parseNSF(data)
+	case bytes.HasPrefix(data, nsfeMagic):
+		n, err = parseNSFe(data[len(nsfeMagic):])
+	default:
+		return nil, fmt.Errorf("%w: missing NESM or NSFE signature", ErrNotNSF)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n.Songs == 0 {
+		return nil, fmt.Errorf("%w: no songs", ErrMalformed)
+	}
+	if n.StartSong < 0 || n.StartSong >= n.Songs {
+		n.StartSong = 0
+	}
+	for len(n.Tracks) < n.Songs {
+		n.Tracks = append(n.Tracks, Track{Length: -1, Fade: -1})
+	}
+	n.Tracks = n.Tracks[:n.Songs]
+	if n.Playlist == nil {
+		for i := 0; i < n.Songs; i++ {
+			n.Playlist = append(n.Playlist, i)
+		}
+	}
+	return n, nil
+}
+
+// parseNSF decodes an NSF file, a 128 byte header followed by the program
+func parseNSF(data []uint8) (*NSF, error) {
+	if len(data) < nsfHeaderSize {
+		return nil, fmt.Errorf("%w: file is only %d bytes, shorter than the %d byte header", ErrMalformed, len(data), nsfHeaderSize)
+	}
+	n := &NSF{
+		Songs:       int(data[0x06]),
+		StartSong:   int(data[0x07]) - 1,
+		LoadAddress: binary.LittleEndian.Uint16(data[0x08:]),
+		InitAddress: binary.LittleEndian.Uint16(data[0x0A:]),
+		PlayAddress: binary.LittleEndian.Uint16(data[0x0C:]),
+		Title:       nsfString(data[0x0E : 0x0E+nsfStringSize]),
+		Artist:      nsfString(data[0x2E : 0x2E+nsfStringSize]),
+		Copyright:   nsfString(data[0x4E : 0x4E+nsfStringSize]),
+		NTSCSpeed:   binary.LittleEndian.Uint16(data[0x6E:]),
+		PALSpeed:    binary.LittleEndian.Uint16(data[0x78:]),
+		Timing:      nsfTiming(data[0x7A]),
+		Chips:       ExpansionChips(data[0x7B]),
+	}
+	copy(n.Banks[:], data[0x70:0x78])
+
+	n.Data = data[nsfHeaderSize:]
+	// NSF2 gives the program length so that metadata can follow it. 0 means the program runs to the end of the file
+	if length := int(data[0x7D]) | int(data[0x7E])<<8 | int(data[0x7F])<<16; data[0x05] >= 2 && length != 0 {
+		if length > len(n.Data) {
+			return nil, fmt.Errorf("%w: header declares %d bytes of program but only %d bytes remain", ErrMalformed, length, len(n.Data))
+		}
+		n.Data = n.Data[:length]
+	}
+	return n, nil
+}
+
+// parseNSFe decodes the chunks of an NSFe file. Each chunk is a 4 byte length, a 4 byte ID and then the data. Chunks
+// with an upper case first letter in their ID are required to play the file, so unknown ones are an error, while
+// unknown optional chunks are skipped
+func parseNSFe(data []uint8) (*NSF, error) {
+	n := &NSF{
+		NTSCSpeed: nsfDefaultNTSCSpeed,
+		PALSpeed:  nsfDefaultPALSpeed,
+	}
+	var info, program bool
+	for {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("%w: file ends without an NEND chunk", ErrMalformed)
+		}
+		length := binary.LittleEndian.Uint32(data)
+		id := string(data[4:8])
+		data = data[8:]
+		if uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("%w: %s chunk is %d bytes but only %d bytes remain", ErrMalformed, id, length, len(data))
+		}
+		chunk := data[:length]
+		data = data[length:]
+
+		switch id {
+		case "INFO":
+			if len(chunk) < 8 {
+				return nil, fmt.Errorf("%w: INFO chunk is only %d bytes", ErrMalformed, len(chunk))
+			}
+			n.LoadAddress = binary.LittleEndian.Uint16(chunk[0:])
+			n.InitAddress = binary.LittleEndian.Uint16(chunk[2:])
+			n.PlayAddress = binary.LittleEndian.Uint16(chunk[4:])
+			n.Timing = nsfTiming(chunk[6])
+			n.Chips = ExpansionChips(chunk[7])
+			n.Songs = 1
+			if len(chunk) > 8 {
+				n.Songs = int(chunk[8])
+			}
+			if len(chunk) > 9 {
+				n.StartSong = int(chunk[9])
+			}
+			info = true
+		case "DATA":
+			n.Data = chunk
+			program = true
+		case "BANK":
+			copy(n.Banks[:], chunk)
+		case "RATE":
+			if len(chunk) >= 2 {
+				n.NTSCSpeed = binary.LittleEndian.Uint16(chunk[0:])
+			}
+			if len(chunk) >= 4 {
+				n.PALSpeed = binary.LittleEndian.Uint16(chunk[2:])
+			}
+		case "auth":
+			strings := nsfStrings(chunk)
+			for i, s := range []*string{&n.Title, &n.Artist, &n.Copyright, &n.Ripper} {
+				if i < len(strings) {
+					*s = strings[i]
+				}
+			}
+		case "tlbl":
+			for i, title := range nsfStrings(chunk) {
+				n.track(i).Title = title
+			}
+		case "time":
+			for i := 0; i+4 <= len(chunk); i += 4 {
+				n.track(i / 4).Length = nsfDuration(chunk[i:])
+			}
+		case "fade":
+			for i := 0; i+4 <= len(chunk); i += 4 {
+				n.track(i / 4).Fade = nsfDuration(chunk[i:])
+			}
+		case "plst":
+			n.Playlist = make([]int, len(chunk))
+			for i, track := range chunk {
+				n.Playlist[i] = int(track)
+			}
+		case "NEND":
+			if !info || !program {
+				return nil, fmt.Errorf("%w: missing INFO or DATA chunk", ErrMalformed)
+			}
+			for _, track := range n.Playlist {
+				if track >= n.Songs {
+					return nil, fmt.Errorf("%w: playlist refers to track %d of %d", ErrMalformed, track+1, n.Songs)
+				}
+			}
+			return n, nil
+		default:
+			if id[0] >= 'A' && id[0] <= 'Z' {
+				return nil, fmt.Errorf("%w: unsupported required chunk %q", ErrMalformed, id)
+			}
+		}
+	}
+}
+
+// track returns track i, adding untitled and untimed tracks up to it if needed
+func (n *NSF) track(i int) *Track {
+	for len(n.Tracks) <= i {
+		n.Tracks = append(n.Tracks, Track{Length: -1, Fade: -1})
+	}
+	return &n.Tracks[i]
+}
+
+// nsfTiming decodes the region byte. Bit 0 selects PAL and bit 1 means the tune supports both regions
+func nsfTiming(region uint8) Timing {
+	switch {
+	case region&0x02 != 0:
+		return MultiRegion
+	case region&0x01 != 0:
+		return PAL
+	}
+	return NTSC
+}
+
+// nsfString decodes a fixed size, zero padded string from the NSF header
+func nsfString(data []uint8) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return string(data)
+}
+
+// nsfStrings decodes a list of zero terminated strings
+func nsfStrings(data []uint8) []string {
+	var strings []string
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			end = len(data)
+		}
+		strings = append(strings, string(data[:end]))
+		if end == len(data) {
+			break
+		}
+		data = data[end+1:]
+	}
+	return strings
+}
+
+// nsfDuration decodes a signed 32 bit number of milliseconds. Negative values mean the time is not given
+func nsfDuration(data []uint8) time.Duration {
+	ms := int32(binary.LittleEndian.Uint32(data))
+	if ms < 0 {
+		return -1
+	}
+	return time.Duration(ms) * time.Millisecond
+}
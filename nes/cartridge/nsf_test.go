@@ -0,0 +1,259 @@
+package cartridge
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// nsfHeader builds an NSF header with the given version and NSF2 program length, followed by the data
+func nsfHeader(version uint8, songs, start uint8, programLength int, data []uint8) []uint8 {
+	header := make([]uint8, nsfHeaderSize)
+	copy(header, nsfMagic)
+	header[0x05] = version
+	header[0x06] = songs
+	header[0x07] = start
+	binary.LittleEndian.PutUint16(header[0x08:], 0x8000)
+	binary.LittleEndian.PutUint16(header[0x0A:], 0x8000)
+	binary.LittleEndian.PutUint16(header[0x0C:], 0x8003)
+	copy(header[0x0E:], "Title")
+	binary.LittleEndian.PutUint16(header[0x6E:], nsfDefaultNTSCSpeed)
+	binary.LittleEndian.PutUint16(header[0x78:], nsfDefaultPALSpeed)
+	header[0x7D] = uint8(programLength)
+	header[0x7E] = uint8(programLength >> 8)
+	header[0x7F] = uint8(programLength >> 16)
+	return append(header, data...)
+}
+
+// nsfeChunk encodes a single NSFe chunk
+func nsfeChunk(id string, data ...uint8) []uint8 {
+	chunk := make([]uint8, 8, 8+len(data))
+	binary.LittleEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], id)
+	return append(chunk, data...)
+}
+
+// nsfeFile joins chunks into an NSFe file
+func nsfeFile(chunks ...[]uint8) []uint8 {
+	file := append([]uint8{}, nsfeMagic...)
+	for _, chunk := range chunks {
+		file = append(file, chunk...)
+	}
+	return file
+}
+
+// nsfeInfo is an INFO chunk for a tune loaded at $8000 with the given number of songs
+func nsfeInfo(songs uint8) []uint8 {
+	return nsfeChunk("INFO", 0x00, 0x80, 0x00, 0x80, 0x03, 0x80, 0x00, 0x00, songs, 0x00)
+}
+
+// nsfeMilliseconds encodes a list of signed millisecond values for the time and fade chunks
+func nsfeMilliseconds(values ...int32) []uint8 {
+	data := make([]uint8, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[4*i:], uint32(v))
+	}
+	return data
+}
+
+func TestParseNSF(t *testing.T) {
+	program := []uint8{0x60, 0x60, 0x60, 0x60}
+	metadata := []uint8{'t', 'l', 'b', 'l'}
+
+	tests := []struct {
+		name      string
+		data      []uint8
+		wantData  []uint8
+		wantStart int
+		wantErr   error
+	}{
+		{
+			name:     "version 1 ignores program length",
+			data:     nsfHeader(1, 2, 1, 2, program),
+			wantData: program,
+		},
+		{
+			name:     "NSF2 program length trims metadata",
+			data:     nsfHeader(2, 2, 1, len(program), append(append([]uint8{}, program...), metadata...)),
+			wantData: program,
+		},
+		{
+			name:     "NSF2 program length of 0 runs to the end",
+			data:     nsfHeader(2, 2, 1, 0, program),
+			wantData: program,
+		},
+		{
+			name:    "NSF2 program length past the end",
+			data:    nsfHeader(2, 2, 1, len(program)+1, program),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:      "starting song is counted from 1",
+			data:      nsfHeader(1, 3, 2, 0, program),
+			wantData:  program,
+			wantStart: 1,
+		},
+		{
+			name:     "starting song of 0",
+			data:     nsfHeader(1, 3, 0, 0, program),
+			wantData: program,
+		},
+		{
+			name:     "starting song past the last song",
+			data:     nsfHeader(1, 3, 4, 0, program),
+			wantData: program,
+		},
+		{
+			name:    "no songs",
+			data:    nsfHeader(1, 0, 1, 0, program),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "short header",
+			data:    nsfHeader(1, 1, 1, 0, nil)[:nsfHeaderSize-1],
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "bad signature",
+			data:    []uint8("NES\x1a"),
+			wantErr: ErrNotNSF,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := ParseNSF(test.data)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(n.Data, test.wantData) {
+				t.Errorf("data = % X, want % X", n.Data, test.wantData)
+			}
+			if n.StartSong != test.wantStart {
+				t.Errorf("start song = %d, want %d", n.StartSong, test.wantStart)
+			}
+			if n.Title != "Title" {
+				t.Errorf("title = %q, want %q", n.Title, "Title")
+			}
+			if len(n.Tracks) != n.Songs || len(n.Playlist) != n.Songs {
+				t.Errorf("%d tracks and %d playlist entries, want %d", len(n.Tracks), len(n.Playlist), n.Songs)
+			}
+		})
+	}
+}
+
+func TestParseNSFe(t *testing.T) {
+	data := nsfeChunk("DATA", 0x60, 0x60, 0x60, 0x60)
+	end := nsfeChunk("NEND")
+	untimed := Track{Length: -1, Fade: -1}
+
+	tests := []struct {
+		name         string
+		data         []uint8
+		wantTracks   []Track
+		wantPlaylist []int
+		wantErr      error
+	}{
+		{
+			name:         "required chunks only",
+			data:         nsfeFile(nsfeInfo(2), data, end),
+			wantTracks:   []Track{untimed, untimed},
+			wantPlaylist: []int{0, 1},
+		},
+		{
+			name:         "unknown optional chunk is skipped",
+			data:         nsfeFile(nsfeInfo(1), nsfeChunk("xtra", 1, 2, 3), data, end),
+			wantTracks:   []Track{untimed},
+			wantPlaylist: []int{0},
+		},
+		{
+			name:    "unknown required chunk",
+			data:    nsfeFile(nsfeInfo(1), nsfeChunk("XTRA", 1, 2, 3), data, end),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "missing INFO",
+			data:    nsfeFile(data, end),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "missing DATA",
+			data:    nsfeFile(nsfeInfo(1), end),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "missing NEND",
+			data:    nsfeFile(nsfeInfo(1), data),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "chunk longer than the file",
+			data:    nsfeFile(nsfeInfo(1), data, []uint8{0x10, 0x00, 0x00, 0x00, 't', 'l', 'b', 'l'}, end),
+			wantErr: ErrMalformed,
+		},
+		{
+			name: "track titles",
+			data: nsfeFile(nsfeInfo(3), data, nsfeChunk("tlbl", []uint8("One\x00Two\x00")...), end),
+			wantTracks: []Track{
+				{Title: "One", Length: -1, Fade: -1},
+				{Title: "Two", Length: -1, Fade: -1},
+				untimed,
+			},
+			wantPlaylist: []int{0, 1, 2},
+		},
+		{
+			name: "lengths and fades",
+			data: nsfeFile(nsfeInfo(3), data,
+				nsfeChunk("time", nsfeMilliseconds(90000, -1)...),
+				nsfeChunk("fade", nsfeMilliseconds(5000, 2500, 0)...),
+				end),
+			wantTracks: []Track{
+				{Length: 90 * time.Second, Fade: 5 * time.Second},
+				{Length: -1, Fade: 2500 * time.Millisecond},
+				{Length: -1, Fade: 0},
+			},
+			wantPlaylist: []int{0, 1, 2},
+		},
+		{
+			name:         "playlist",
+			data:         nsfeFile(nsfeInfo(3), data, nsfeChunk("plst", 2, 0, 2), end),
+			wantTracks:   []Track{untimed, untimed, untimed},
+			wantPlaylist: []int{2, 0, 2},
+		},
+		{
+			name:    "playlist past the last track",
+			data:    nsfeFile(nsfeInfo(3), data, nsfeChunk("plst", 0, 3), end),
+			wantErr: ErrMalformed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := ParseNSF(test.data)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(n.Tracks, test.wantTracks) {
+				t.Errorf("tracks = %+v, want %+v", n.Tracks, test.wantTracks)
+			}
+			if !reflect.DeepEqual(n.Playlist, test.wantPlaylist) {
+				t.Errorf("playlist = %v, want %v", n.Playlist, test.wantPlaylist)
+			}
+			if n.PlayAddress != 0x8003 || n.NTSCSpeed != nsfDefaultNTSCSpeed || n.PALSpeed != nsfDefaultPALSpeed {
+				t.Errorf("play $%04X at %d/%d us, want $8003 at the default rates", n.PlayAddress, n.NTSCSpeed, n.PALSpeed)
+			}
+		})
+	}
+}
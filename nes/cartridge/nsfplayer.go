@@ -0,0 +1,433 @@
+package cartridge
+
+import "github.com/kevinwmiller/nesgo/nes/apu"
+
+// nsfPlayer is the synthetic board an NSF is played on. It maps the tune's program using the NSF bank switching
+// scheme, provides the expansion sound chips the tune asks for and holds a small driver program that initialises the
+// console, calls INIT with the song number and then calls PLAY whenever a timer expires.
+// http://wiki.nesdev.com/w/index.php/NSF
+type nsfPlayer struct {
+	nametables
+	chr [defaultCHRRAM]uint8
+
+	// The program padded to whole 4KB banks, starting at the bank containing the load address
+	image []uint8
+	// The banks mapped at $6000-$FFFF. Only FDS tunes can switch the banks at $6000 and $7000
+	banks  [nsfSlots]int
+	prgRAM [0x2000]uint8
+	// FDS tunes run from RAM covering $6000-$FFFF. Switching a bank copies it into the RAM
+	fdsRAM []uint8
+
+	driver []uint8
+	song   uint8
+	region uint8
+
+	// CPU cycles between PLAY calls and until the next one. The timer starts once INIT returns
+	playPeriod int
+	playTimer  int
+	playing    bool
+	playDue    bool
+
+	chips    []apu.Expansion
+	vrc6     *vrc6Audio
+	vrc7     *opll
+	fds      *fdsAudio
+	mmc5     *mmc5Audio
+	n163     *n163Audio
+	sunsoft  *sunsoft5B
+	exRAM    [0x0400]uint8
+	multiply [2]uint8
+}
+
+const (
+	nsfBankSize = 0x1000
+	nsfSlots    = 10
+	nsfRAMStart = 0x6000
+	// Writes to $5FF6-$5FFF switch the 4KB banks at $6000-$FFFF
+	nsfBankSwitch uint16 = 0x5FF6
+
+	// The driver lives in otherwise unused cartridge space with its registers at the end
+	nsfDriverStart uint16 = 0x4100
+	nsfDriverEnd   uint16 = 0x41FF
+	nsfSong        uint16 = 0x41FC
+	nsfRegion      uint16 = 0x41FD
+	// Writing starts the PLAY timer. Reading returns bit 7 set if PLAY is due, and clears it
+	nsfPlayTimer uint16 = 0x41FE
+
+	vectorsStart uint16 = 0xFFFA
+
+	nsfMMC5ExRAMStart uint16 = 0x5C00
+	nsfMMC5ExRAMEnd   uint16 = 0x5FF5
+)
+
+// nsfDriver is the player program, assembled at $4100. The operands of the two JSRs are filled in with the tune's
+// INIT and PLAY addresses
+var nsfDriver = []uint8{
+	0x78,       // $4100 SEI
+	0xD8,       // $4101 CLD
+	0xA2, 0xFF, // $4102 LDX #$FF
+	0x9A, // $4104 TXS
+	0xE8, // $4105 INX
+	0x8A, // $4106 TXA
+	// Clear the internal RAM
+	0x95, 0x00, // $4107 STA $00,X
+	0x9D, 0x00, 0x01, // $4109 STA $0100,X
+	0x9D, 0x00, 0x02, // $410C STA $0200,X
+	0x9D, 0x00, 0x03, // $410F STA $0300,X
+	0x9D, 0x00, 0x04, // $4112 STA $0400,X
+	0x9D, 0x00, 0x05, // $4115 STA $0500,X
+	0x9D, 0x00, 0x06, // $4118 STA $0600,X
+	0x9D, 0x00, 0x07, // $411B STA $0700,X
+	0xE8,       // $411E INX
+	0xD0, 0xE6, // $411F BNE $4107
+	// Silence the APU
+	0xA2, 0x13, // $4121 LDX #$13
+	0x9D, 0x00, 0x40, // $4123 STA $4000,X
+	0xCA,       // $4126 DEX
+	0x10, 0xFA, // $4127 BPL $4123
+	0xA9, 0x0F, // $4129 LDA #$0F
+	0x8D, 0x15, 0x40, // $412B STA $4015
+	0xA9, 0x40, // $412E LDA #$40
+	0x8D, 0x17, 0x40, // $4130 STA $4017
+	// Call INIT with the song in A and the region in X, then start the timer
+	0xAD, 0xFC, 0x41, // $4133 LDA $41FC
+	0xAE, 0xFD, 0x41, // $4136 LDX $41FD
+	0x20, 0x00, 0x00, // $4139 JSR INIT
+	0x8D, 0xFE, 0x41, // $413C STA $41FE
+	// Call PLAY each time the timer expires
+	0x2C, 0xFE, 0x41, // $413F BIT $41FE
+	0x10, 0xFB, // $4142 BPL $413F
+	0x20, 0x00, 0x00, // $4144 JSR PLAY
+	0x4C, 0x3F, 0x41, // $4147 JMP $413F
+	// NMIs and IRQs are ignored
+	0x40, // $414A RTI
+}
+
+// Offsets into nsfDriver
+const (
+	nsfDriverInit = 0x3A
+	nsfDriverPlay = 0x45
+	nsfDriverRTI  = 0x4A
+)
+
+// Cartridge returns a cartridge that plays song, counting from 0, when the console is reset. cpuRate is the CPU clock
+// frequency in Hz of the console it will run on, which sets how many cycles apart the PLAY calls are. Multi-region
+// tunes are played at NTSC timing unless Timing is changed first. Dendy consoles run at 50Hz so they get the PAL play
+// rate
+func (n *NSF) Cartridge(song int, cpuRate float64) *Cartridge {
+	timing := n.Timing
+	if timing == MultiRegion {
+		timing = NTSC
+	}
+	p := &nsfPlayer{
+		song:   uint8(song),
+		driver: make([]uint8, len(nsfDriver)),
+	}
+	copy(p.driver, nsfDriver)
+	p.driver[nsfDriverInit] = uint8(n.InitAddress)
+	p.driver[nsfDriverInit+1] = uint8(n.InitAddress >> 8)
+	p.driver[nsfDriverPlay] = uint8(n.PlayAddress)
+	p.driver[nsfDriverPlay+1] = uint8(n.PlayAddress >> 8)
+
+	// A play speed of 0 is treated as the standard frame rate of the region
+	speed := n.NTSCSpeed
+	if speed == 0 {
+		speed = nsfDefaultNTSCSpeed
+	}
+	if timing != NTSC {
+		speed = n.PALSpeed
+		if speed == 0 {
+			speed = nsfDefaultPALSpeed
+		}
+		p.region = 1
+	}
+	p.playPeriod = int(float64(speed) * cpuRate / 1e6)
+
+	// The image starts at the 4KB boundary below the load address, so banks line up with the NSF bank numbers
+	padding := int(n.LoadAddress) % nsfBankSize
+	p.image = make([]uint8, (padding+len(n.Data)+nsfBankSize-1)/nsfBankSize*nsfBankSize)
+	copy(p.image[padding:], n.Data)
+	for slot := range p.banks {
+		p.banks[slot] = -1
+		if n.Banked() {
+			// FDS tunes start with the banks for $E000 and $F000 at $6000 and $7000 as well
+			if slot >= 2 {
+				p.banks[slot] = int(n.Banks[slot-2])
+			} else if n.Chips&ChipFDS != 0 {
+				p.banks[slot] = int(n.Banks[slot+6])
+			}
+		} else if start := nsfRAMStart + slot*nsfBankSize; start >= int(n.LoadAddress)&^(nsfBankSize-1) {
+			p.banks[slot] = (start - int(n.LoadAddress)&^(nsfBankSize-1)) / nsfBankSize
+		}
+	}
+
+	if n.Chips&ChipVRC6 != 0 {
+		p.vrc6 = &vrc6Audio{}
+		p.chips = append(p.chips, p.vrc6)
+	}
+	if n.Chips&ChipVRC7 != 0 {
+		chip := newOPLL()
+		p.vrc7 = &chip
+		p.chips = append(p.chips, p.vrc7)
+	}
+	if n.Chips&ChipFDS != 0 {
+		chip := newFDSAudio()
+		p.fds = &chip
+		p.chips = append(p.chips, p.fds)
+		p.fdsRAM = make([]uint8, nsfSlots*nsfBankSize)
+		for slot := range p.banks {
+			p.switchBank(slot, p.banks[slot])
+		}
+	}
+	if n.Chips&ChipMMC5 != 0 {
+		p.mmc5 = &mmc5Audio{}
+		p.chips = append(p.chips, p.mmc5)
+	}
+	if n.Chips&ChipN163 != 0 {
+		p.n163 = &n163Audio{}
+		p.chips = append(p.chips, p.n163)
+	}
+	if n.Chips&ChipSunsoft5B != 0 {
+		chip := newSunsoft5B()
+		p.sunsoft = &chip
+		p.chips = append(p.chips, p.sunsoft)
+	}
+
+	cart := &Cartridge{
+		Header: Header{
+			PRGROMSize: len(p.image),
+			CHRRAMSize: defaultCHRRAM,
+			Mirroring:  Vertical,
+			Timing:     timing,
+		},
+		PRG:    p.image,
+		mapper: p,
+	}
+	cart.ticker = p
+	p.mirroring = Vertical
+	return cart
+}
+
+// bank returns the 4KB of the image in bank, or nil if the bank is past the end of it
+func (p *nsfPlayer) bank(bank int) []uint8 {
+	start := bank * nsfBankSize
+	if bank < 0 || start >= len(p.image) {
+		return nil
+	}
+	return p.image[start : start+nsfBankSize]
+}
+
+// switchBank maps bank into one of the 4KB slots at $6000-$FFFF. FDS tunes get a copy of the bank in RAM
+func (p *nsfPlayer) switchBank(slot, bank int) {
+	p.banks[slot] = bank
+	if p.fdsRAM == nil {
+		return
+	}
+	ram := p.fdsRAM[slot*nsfBankSize : (slot+1)*nsfBankSize]
+	if data := p.bank(bank); data != nil {
+		copy(ram, data)
+	} else {
+		for i := range ram {
+			ram[i] = 0
+		}
+	}
+}
+
+// Tick runs the PLAY timer and clocks the sound chips
+func (p *nsfPlayer) Tick() {
+	if p.playing {
+		p.playTimer--
+		if p.playTimer <= 0 {
+			p.playTimer += p.playPeriod
+			p.playDue = true
+		}
+	}
+	if p.vrc6 != nil {
+		p.vrc6.tick()
+	}
+	if p.vrc7 != nil {
+		p.vrc7.tick()
+	}
+	if p.fds != nil {
+		p.fds.tick()
+	}
+	if p.mmc5 != nil {
+		p.mmc5.tick()
+	}
+	if p.n163 != nil {
+		p.n163.tick()
+	}
+	if p.sunsoft != nil {
+		p.sunsoft.tick()
+	}
+}
+
+func (p *nsfPlayer) audio() apu.Expansion {
+	if len(p.chips) == 0 {
+		return nil
+	}
+	return expansionMix(p.chips)
+}
+
+func (p *nsfPlayer) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= vectorsStart:
+		// Every vector points into the driver: reset to its start, NMI and IRQ to an RTI
+		vector := nsfDriverStart + nsfDriverRTI
+		if address == 0xFFFC || address == 0xFFFD {
+			vector = nsfDriverStart
+		}
+		return uint8(vector >> (8 * (address & 0x01))), 0xFF
+	case address >= nsfRAMStart:
+		data := p.readMemory(address)
+		if p.mmc5 != nil {
+			p.mmc5.observeRead(address, data)
+		}
+		return data, 0xFF
+	case p.mmc5 != nil && address >= nsfMMC5ExRAMStart && address <= nsfMMC5ExRAMEnd:
+		return p.exRAM[address-nsfMMC5ExRAMStart], 0xFF
+	case p.mmc5 != nil && (address == 0x5205 || address == 0x5206):
+		product := uint16(p.multiply[0]) * uint16(p.multiply[1])
+		return uint8(product >> (8 * (address - 0x5205))), 0xFF
+	case p.mmc5 != nil && (address == 0x5010 || address == 0x5015):
+		data, _ := p.mmc5.read(address)
+		return data, 0xFF
+	case p.n163 != nil && address >= 0x4800 && address <= 0x4FFF:
+		return p.n163.readData(), 0xFF
+	case address == nsfSong:
+		return p.song, 0xFF
+	case address == nsfRegion:
+		return p.region, 0xFF
+	case address == nsfPlayTimer:
+		var due uint8
+		if p.playDue {
+			due = 0x80
+		}
+		p.playDue = false
+		return due, 0xFF
+	case address >= nsfDriverStart && address <= nsfDriverEnd:
+		if offset := int(address - nsfDriverStart); offset < len(p.driver) {
+			return p.driver[offset], 0xFF
+		}
+	case p.fds != nil:
+		return p.fds.read(address)
+	}
+	return 0, 0
+}
+
+// PeekCPU reads without feeding the MMC5 PCM channel, acknowledging the PLAY timer or other read side effects
+func (p *nsfPlayer) PeekCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= vectorsStart:
+		return p.ReadCPU(address)
+	case address >= nsfRAMStart:
+		return p.readMemory(address), 0xFF
+	case p.mmc5 != nil && (address == 0x5010 || address == 0x5015):
+		data, _ := p.mmc5.peek(address)
+		return data, 0xFF
+	case p.n163 != nil && address >= 0x4800 && address <= 0x4FFF:
+		return p.n163.peekData(), 0xFF
+	case address == nsfPlayTimer:
+		if p.playDue {
+			return 0x80, 0xFF
+		}
+		return 0, 0xFF
+	}
+	return p.ReadCPU(address)
+}
+
+// readMemory reads the program or RAM at $6000-$FFFF
+func (p *nsfPlayer) readMemory(address uint16) uint8 {
+	offset := int(address - nsfRAMStart)
+	if p.fdsRAM != nil {
+		return p.fdsRAM[offset]
+	}
+	if address < prgROMStart {
+		return p.prgRAM[offset]
+	}
+	if data := p.bank(p.banks[offset/nsfBankSize]); data != nil {
+		return data[offset%nsfBankSize]
+	}
+	return 0
+}
+
+func (p *nsfPlayer) WriteCPU(address uint16, data uint8) {
+	switch {
+	case address >= nsfRAMStart:
+		if p.fdsRAM != nil {
+			p.fdsRAM[address-nsfRAMStart] = data
+		} else if address < prgROMStart {
+			p.prgRAM[address-nsfRAMStart] = data
+		}
+		p.writeChip(address, data)
+	case address >= nsfBankSwitch:
+		slot := int(address - nsfBankSwitch)
+		if slot >= 2 || p.fdsRAM != nil {
+			p.switchBank(slot, int(data))
+		}
+	case p.mmc5 != nil && address >= nsfMMC5ExRAMStart:
+		p.exRAM[address-nsfMMC5ExRAMStart] = data
+	case p.mmc5 != nil && (address == 0x5205 || address == 0x5206):
+		p.multiply[address-0x5205] = data
+	case p.mmc5 != nil && address >= 0x5000 && address <= 0x5015:
+		p.mmc5.write(address, data)
+	case p.n163 != nil && address >= 0x4800 && address <= 0x4FFF:
+		p.n163.writeData(data)
+	case address == nsfPlayTimer:
+		p.playing = true
+		p.playTimer = p.playPeriod
+	case p.fds != nil:
+		p.fds.write(address, data)
+	}
+}
+
+// writeChip passes writes to $8000-$FFFF to the sound chips that have registers there. The Namco 163's address port
+// takes $F800-$FFFF from the Sunsoft 5B's data port when a tune uses both
+func (p *nsfPlayer) writeChip(address uint16, data uint8) {
+	if p.vrc6 != nil && address >= 0x9000 && address <= 0xB002 && address&0x0FFF <= 0x0003 {
+		p.vrc6.write(address, data)
+	}
+	switch {
+	case p.vrc7 != nil && address == vrc7AudioAddress:
+		p.vrc7.selectRegister(data)
+	case p.vrc7 != nil && address == vrc7AudioData:
+		p.vrc7.write(data)
+	case p.n163 != nil && address >= 0xF800:
+		p.n163.writeAddress(data)
+	case p.sunsoft != nil && address >= 0xE000:
+		p.sunsoft.writeData(data)
+	case p.sunsoft != nil && address >= 0xC000 && address <= 0xDFFF:
+		p.sunsoft.writeAddress(data)
+	}
+}
+
+func (p *nsfPlayer) ReadPPU(address uint16) uint8 {
+	if address <= patternTableEnd {
+		return p.chr[address]
+	}
+	return p.readNametable(address)
+}
+
+func (p *nsfPlayer) WritePPU(address uint16, data uint8) {
+	if address <= patternTableEnd {
+		p.chr[address] = data
+		return
+	}
+	p.writeNametable(address, data)
+}
+
+// expansionMix combines several expansion chips into one output. Each chip is weighted by its own volume, so the mix
+// is already on the APU's scale
+type expansionMix []apu.Expansion
+
+func (m expansionMix) Sample() float32 {
+	var sample float32
+	for _, chip := range m {
+		sample += chip.Sample() * chip.Volume()
+	}
+	return sample
+}
+
+func (m expansionMix) Volume() float32 {
+	return 1
+}
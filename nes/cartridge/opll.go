@@ -0,0 +1,349 @@
+package cartridge
+
+import "math"
+
+// opll is the VRC7's FM synthesiser, a cut down YM2413 with six two-operator channels, no rhythm mode and its own set
+// of 15 built in instruments plus one user defined instrument. Each channel has a modulator whose output modulates the
+// phase of a carrier, and each operator has its own envelope. This is a floating point model of the chip rather than
+// a bit exact one.
+// http://wiki.nesdev.com/w/index.php/VRC7_audio
+type opll struct {
+	address  uint8
+	custom   [8]uint8
+	channels [opllChannels]opllChannel
+
+	// CPU cycles until the next sample
+	cycles int
+	output float32
+
+	// Tremolo and vibrato oscillators, shared by every channel, as a fraction of their period
+	amPhase float64
+	pmPhase float64
+}
+
+type opllChannel struct {
+	fnum       uint16
+	block      uint8
+	keyOn      bool
+	sustain    bool
+	instrument uint8
+	volume     uint8
+
+	modulator opllOperator
+	carrier   opllOperator
+	// The last two modulator outputs, averaged for feedback
+	feedback [2]float64
+}
+
+type opllOperator struct {
+	// The phase as a fraction of a cycle
+	phase float64
+	// The envelope attenuation in dB
+	attenuation float64
+	stage       envelopeStage
+}
+
+type envelopeStage uint8
+
+const (
+	envelopeOff envelopeStage = iota
+	envelopeAttack
+	envelopeDecay
+	envelopeSustain
+	envelopeRelease
+)
+
+const (
+	opllChannels = 6
+	// The synthesiser produces a sample every 72 cycles of its 3.58MHz clock, every 36 CPU cycles
+	opllCyclesPerSample = 36
+	opllSampleRate      = 49716.0
+
+	// The attenuation at which an operator is silent
+	opllMaxAttenuation = 48.0
+	// Times at rate 1 for the envelope to go from silent to full volume and from full volume to silent, in seconds.
+	// Each increase of the rate halves them
+	opllAttackTime = 1.73
+	opllDecayTime  = 20.9
+	// The rate used when a note is released with the channel's sustain bit set, and by percussive instruments
+	opllSustainRelease    = 5
+	opllPercussiveRelease = 7
+
+	opllAMDepth     = 4.8
+	opllAMFrequency = 3.7
+	opllPMDepth     = 0.004
+	opllPMFrequency = 6.4
+	// How far the modulator's full output moves the carrier's phase, in cycles
+	opllModulation = 2.0
+)
+
+// vrc7Patches are the built in instruments, as dumped from the chip. Each is 8 bytes in the same layout as the user
+// instrument registers $00-$07
+var vrc7Patches = [15][8]uint8{
+	{0x03, 0x21, 0x05, 0x06, 0xE8, 0x81, 0x42, 0x27},
+	{0x13, 0x41, 0x14, 0x0D, 0xD8, 0xF6, 0x23, 0x12},
+	{0x11, 0x11, 0x08, 0x08, 0xFA, 0xB2, 0x20, 0x12},
+	{0x31, 0x61, 0x0C, 0x07, 0xA8, 0x64, 0x61, 0x27},
+	{0x32, 0x21, 0x1E, 0x06, 0xE1, 0x76, 0x01, 0x28},
+	{0x02, 0x01, 0x06, 0x00, 0xA3, 0xE2, 0xF4, 0xF4},
+	{0x21, 0x61, 0x1D, 0x07, 0x82, 0x81, 0x11, 0x07},
+	{0x23, 0x21, 0x22, 0x17, 0xA2, 0x72, 0x01, 0x17},
+	{0x35, 0x11, 0x25, 0x00, 0x40, 0x73, 0x72, 0x01},
+	{0xB5, 0x01, 0x0F, 0x0F, 0xA8, 0xA5, 0x51, 0x02},
+	{0x17, 0xC1, 0x24, 0x07, 0xF8, 0xF8, 0x22, 0x12},
+	{0x71, 0x23, 0x11, 0x06, 0x65, 0x74, 0x18, 0x16},
+	{0x01, 0x02, 0xD3, 0x05, 0xC9, 0x95, 0x03, 0x02},
+	{0x61, 0x63, 0x0C, 0x00, 0x94, 0xC0, 0x33, 0xF6},
+	{0x21, 0x72, 0x0D, 0x00, 0xC1, 0xD5, 0x56, 0x06},
+}
+
+// opllMultipliers are the frequency multipliers selected by the low 4 bits of an operator's first patch byte
+var opllMultipliers = [16]float64{0.5, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 10, 12, 12, 15, 15}
+
+// opllKeyScale is the attenuation in dB at the top octave for each of the upper 4 bits of the frequency number
+var opllKeyScale = [16]float64{
+	0, 18, 24, 27.75, 30, 32.25, 33.75, 35.25, 36, 37.5, 38.25, 39, 39.75, 40.5, 41.25, 42,
+}
+
+// Patch bits for each operator
+const (
+	opllAM           uint8 = 0x80
+	opllVibrato      uint8 = 0x40
+	opllSustained    uint8 = 0x20
+	opllKeyScaleHigh uint8 = 0x10
+)
+
+func newOPLL() opll {
+	o := opll{}
+	o.reset()
+	return o
+}
+
+// reset silences every channel and clears the registers
+func (o *opll) reset() {
+	*o = opll{cycles: opllCyclesPerSample}
+	for i := range o.channels {
+		o.channels[i].modulator.attenuation = opllMaxAttenuation
+		o.channels[i].carrier.attenuation = opllMaxAttenuation
+	}
+}
+
+// selectRegister sets the register written by the next write to the data port
+func (o *opll) selectRegister(address uint8) {
+	o.address = address
+}
+
+func (o *opll) write(data uint8) {
+	switch {
+	case o.address < 0x08:
+		o.custom[o.address] = data
+		return
+	case o.address&0x0F >= opllChannels:
+		return
+	}
+
+	c := &o.channels[o.address&0x0F]
+	switch o.address & 0xF0 {
+	case 0x10:
+		c.fnum = c.fnum&0x100 | uint16(data)
+	case 0x20:
+		c.fnum = c.fnum&0xFF | uint16(data&0x01)<<8
+		c.block = (data >> 1) & 0x07
+		c.sustain = data&0x20 != 0
+		keyOn := data&0x10 != 0
+		switch {
+		case keyOn && !c.keyOn:
+			c.modulator.keyOn()
+			c.carrier.keyOn()
+			c.feedback = [2]float64{}
+		case !keyOn && c.keyOn:
+			c.modulator.stage = envelopeRelease
+			c.carrier.stage = envelopeRelease
+		}
+		c.keyOn = keyOn
+	case 0x30:
+		c.instrument = data >> 4
+		c.volume = data & 0x0F
+	}
+}
+
+func (op *opllOperator) keyOn() {
+	op.phase = 0
+	op.stage = envelopeAttack
+}
+
+// tick is called every CPU cycle
+func (o *opll) tick() {
+	o.cycles--
+	if o.cycles > 0 {
+		return
+	}
+	o.cycles = opllCyclesPerSample
+
+	o.amPhase = math.Mod(o.amPhase+opllAMFrequency/opllSampleRate, 1)
+	o.pmPhase = math.Mod(o.pmPhase+opllPMFrequency/opllSampleRate, 1)
+	// Tremolo is a triangle from 0 to the full depth. Vibrato is a triangle either side of the pitch
+	am := opllAMDepth * (1 - math.Abs(2*o.amPhase-1))
+	pm := 1 + opllPMDepth*(1-math.Abs(4*o.pmPhase-2))
+
+	var output float64
+	for i := range o.channels {
+		output += o.channels[i].sample(o.patch(i), am, pm)
+	}
+	o.output = float32(output / opllChannels)
+}
+
+// patch returns the instrument used by a channel
+func (o *opll) patch(channel int) *[8]uint8 {
+	instrument := o.channels[channel].instrument
+	if instrument == 0 {
+		return &o.custom
+	}
+	return &vrc7Patches[instrument-1]
+}
+
+// sample generates the next output of a channel
+func (c *opllChannel) sample(patch *[8]uint8, am, pm float64) float64 {
+	// The key scale rate offset comes from the octave and the top bit of the frequency number
+	keyScale := int(c.block)<<1 | int(c.fnum>>8)
+
+	// Modulator
+	c.modulator.envelope(patch[0], patch[4], patch[6], keyScale, c.sustain)
+	c.modulator.advance(patch[0], c.fnum, c.block, pm)
+	attenuation := c.modulator.attenuation + float64(patch[2]&0x3F)*0.75 + c.keyScaleLevel(patch[2]>>6)
+	if patch[0]&opllAM != 0 {
+		attenuation += am
+	}
+	var feedback float64
+	if fb := patch[3] & 0x07; fb != 0 {
+		feedback = (c.feedback[0] + c.feedback[1]) / 2 * math.Exp2(float64(fb)-1) / 32
+	}
+	modulation := wave(c.modulator.phase+feedback, patch[3]&0x08 != 0) * level(attenuation)
+	c.feedback[1], c.feedback[0] = c.feedback[0], modulation
+
+	// Carrier
+	c.carrier.envelope(patch[1], patch[5], patch[7], keyScale, c.sustain)
+	c.carrier.advance(patch[1], c.fnum, c.block, pm)
+	attenuation = c.carrier.attenuation + float64(c.volume)*3 + c.keyScaleLevel(patch[3]>>6)
+	if patch[1]&opllAM != 0 {
+		attenuation += am
+	}
+	return wave(c.carrier.phase+modulation*opllModulation, patch[3]&0x10 != 0) * level(attenuation)
+}
+
+// keyScaleLevel returns the attenuation applied to higher notes. ksl selects 0, 1.5, 3 or 6dB per octave
+func (c *opllChannel) keyScaleLevel(ksl uint8) float64 {
+	if ksl == 0 {
+		return 0
+	}
+	attenuation := opllKeyScale[c.fnum>>5] - 6*float64(7-c.block)
+	if attenuation <= 0 {
+		return 0
+	}
+	return attenuation / float64(uint(1)<<(3-ksl))
+}
+
+// advance moves the operator's phase on by one sample
+func (op *opllOperator) advance(settings uint8, fnum uint16, block uint8, pm float64) {
+	increment := float64(uint32(fnum)<<block) / (1 << 19) * opllMultipliers[settings&0x0F]
+	if settings&opllVibrato != 0 {
+		increment *= pm
+	}
+	op.phase = math.Mod(op.phase+increment, 1)
+}
+
+// envelope moves the operator's envelope on by one sample. rates holds the attack and decay rates and levels holds
+// the sustain level and release rate
+func (op *opllOperator) envelope(settings, rates, levels uint8, keyScale int, channelSustain bool) {
+	if settings&opllKeyScaleHigh == 0 {
+		keyScale >>= 2
+	}
+	sustained := settings&opllSustained != 0
+
+	switch op.stage {
+	case envelopeAttack:
+		op.attenuation -= op.attenuation * attackStep(rates>>4, keyScale)
+		if op.attenuation < 0.1 {
+			op.attenuation = 0
+			op.stage = envelopeDecay
+		}
+	case envelopeDecay:
+		op.attenuation += decayStep(rates&0x0F, keyScale)
+		if sustainLevel := float64(levels>>4) * 3; op.attenuation >= sustainLevel {
+			op.attenuation = sustainLevel
+			op.stage = envelopeSustain
+		}
+	case envelopeSustain:
+		// Percussive instruments keep fading at the release rate while the key is held
+		if !sustained {
+			op.attenuation += decayStep(levels&0x0F, keyScale)
+		}
+	case envelopeRelease:
+		rate := levels & 0x0F
+		switch {
+		case channelSustain:
+			rate = opllSustainRelease
+		case !sustained:
+			rate = opllPercussiveRelease
+		}
+		op.attenuation += decayStep(rate, keyScale)
+	}
+	if op.attenuation >= opllMaxAttenuation {
+		op.attenuation = opllMaxAttenuation
+		if op.stage != envelopeAttack {
+			op.stage = envelopeOff
+		}
+	}
+}
+
+// attackStep returns the fraction of the attenuation removed each sample during the attack. The attack is
+// exponential and reaches full volume in roughly the attack time
+func attackStep(rate uint8, keyScale int) float64 {
+	if rate == 0 {
+		return 0
+	}
+	effective := int(rate)*4 + keyScale
+	if effective >= 60 {
+		return 1
+	}
+	seconds := opllAttackTime * math.Exp2(-float64(effective-4)/4)
+	return 1 - math.Exp(math.Log(0.002)/(seconds*opllSampleRate))
+}
+
+// decayStep returns the attenuation added each sample in dB during the decay and release
+func decayStep(rate uint8, keyScale int) float64 {
+	if rate == 0 {
+		return 0
+	}
+	effective := int(rate)*4 + keyScale
+	seconds := opllDecayTime * math.Exp2(-float64(effective-4)/4)
+	return opllMaxAttenuation / (seconds * opllSampleRate)
+}
+
+// wave returns the operator waveform at phase. The rectified waveform silences the negative half of the sine
+func wave(phase float64, rectified bool) float64 {
+	s := math.Sin(2 * math.Pi * phase)
+	if rectified && s < 0 {
+		return 0
+	}
+	return s
+}
+
+// level converts an attenuation in dB to a linear level
+func level(attenuation float64) float64 {
+	if attenuation >= opllMaxAttenuation {
+		return 0
+	}
+	return math.Pow(10, -attenuation/20)
+}
+
+func (o *opll) Sample() float32 {
+	return o.output
+}
+
+// A single FM channel at full volume is a little louder than an APU pulse. The output is the average of the 6 channels
+const opllVolume = apuPulseLevel * 1.5 * opllChannels
+
+func (o *opll) Volume() float32 {
+	return opllVolume
+}
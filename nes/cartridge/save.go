@@ -0,0 +1,137 @@
+package cartridge
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// saveExtension is the extension of battery save files. They hold the raw contents of the battery backed memory
+const saveExtension = ".sav"
+
+// batteryBacked is implemented by mappers with memory that keeps its contents while the console is off
+type batteryBacked interface {
+	batteryRAM() []uint8
+}
+
+// SavePath returns the path of the save file for the ROM at romPath. Saves are kept next to the ROM unless dir is set
+func SavePath(romPath, dir string) string {
+	name := strings.TrimSuffix(filepath.Base(romPath), filepath.Ext(romPath)) + saveExtension
+	if dir == "" {
+		dir = filepath.Dir(romPath)
+	}
+	return filepath.Join(dir, name)
+}
+
+// batteryRAM returns the cartridge's battery backed memory, or nil if it has none
+func (c *Cartridge) batteryRAM() []uint8 {
+	if !c.Battery {
+		return nil
+	}
+	if b, ok := c.mapper.(batteryBacked); ok {
+		return b.batteryRAM()
+	}
+	return nil
+}
+
+// HasBattery returns true if the cartridge has memory that should be saved between sessions
+func (c *Cartridge) HasBattery() bool {
+	return len(c.batteryRAM()) > 0
+}
+
+// LoadSave loads the battery backed memory from the save file at path. A missing save file is not an error. Save
+// files of a different size are loaded as far as they fit
+func (c *Cartridge) LoadSave(path string) error {
+	ram := c.batteryRAM()
+	if len(ram) == 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	copy(ram, data)
+	return nil
+}
+
+// WriteSave writes the battery backed memory to the save file at path. The save is written to a temporary file in
+// the same directory which is then renamed over the old save, so the save file is always either the old or the new
+// contents even if the emulator is killed part way through
+func (c *Cartridge) WriteSave(path string) error {
+	ram := c.batteryRAM()
+	if len(ram) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(ram); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Saver writes a cartridge's save file whenever the battery backed memory has changed. Register it with the clock to
+// flush the save periodically and call Flush before exiting. Saves are only written between clock ticks so the memory
+// is never written while it is being copied
+type Saver struct {
+	cart  *Cartridge
+	path  string
+	saved []uint8
+	err   error
+}
+
+// NewSaver returns a Saver for the cartridge's save file at path. The memory as it is now is taken to already be saved
+func NewSaver(cart *Cartridge, path string) *Saver {
+	return &Saver{
+		cart:  cart,
+		path:  path,
+		saved: append([]uint8(nil), cart.batteryRAM()...),
+	}
+}
+
+// Tick flushes the save. Errors are kept and can be checked with Err
+func (s *Saver) Tick() {
+	if err := s.Flush(); err != nil {
+		s.err = err
+	}
+}
+
+// Flush writes the save file if the memory has changed since it was last written
+func (s *Saver) Flush() error {
+	ram := s.cart.batteryRAM()
+	if bytes.Equal(ram, s.saved) {
+		return nil
+	}
+	if err := s.cart.WriteSave(s.path); err != nil {
+		return err
+	}
+	s.saved = append(s.saved[:0], ram...)
+	return nil
+}
+
+// Err returns the last error from a periodic flush
+func (s *Saver) Err() error {
+	return s.err
+}
@@ -0,0 +1,204 @@
+package cartridge
+
+import "math"
+
+// sunsoft5B is the Sunsoft 5B's sound hardware, a YM2149F which is a variant of the AY-3-8910. Three square wave
+// channels, a noise generator and an envelope generator that any channel can use for its volume. The 5B divides its
+// clock by 2 before the tone and noise dividers, so they run at the CPU clock / 16 like on an AY-3-8910 clocked at
+// half the CPU rate.
+// http://wiki.nesdev.com/w/index.php/Sunsoft_5B_audio
+type sunsoft5B struct {
+	address   uint8
+	registers [16]uint8
+
+	tones [3]s5bTone
+	// Cycles left until the tone, noise and envelope generators are next clocked
+	divider int
+
+	noiseTimer uint16
+	noise      uint32
+
+	envelopeTimer uint16
+	envelope      s5bEnvelope
+}
+
+type s5bTone struct {
+	timer  uint16
+	output bool
+}
+
+// s5bEnvelope steps through 32 levels. The shape register selects whether it rises or falls and what happens at the end
+// of each pass
+type s5bEnvelope struct {
+	step    uint8
+	attack  bool
+	holding bool
+}
+
+// Sunsoft 5B registers, written by selecting one at $C000 and writing its value at $E000
+const (
+	s5bToneLow       = 0x00
+	s5bNoisePeriod   = 0x06
+	s5bMixer         = 0x07
+	s5bChannelVolume = 0x08
+	s5bEnvelopeLow   = 0x0B
+	s5bEnvelopeHigh  = 0x0C
+	s5bShape         = 0x0D
+)
+
+// Envelope shape bits
+const (
+	s5bHold      uint8 = 0x01
+	s5bAlternate uint8 = 0x02
+	s5bAttack    uint8 = 0x04
+	s5bContinue  uint8 = 0x08
+)
+
+const (
+	s5bClockDivider = 16
+	s5bUseEnvelope  = 0x10
+	s5bSteps        = 32
+	// Each step of the 5 bit volume scale is 1.5dB. The 4 bit channel volumes use every other step
+	s5bStepDB = 1.5
+	// A channel at full volume is about twice as loud as an APU pulse at full volume
+	s5bVolume = apuPulseLevel * 2 * 3
+)
+
+// s5bLevels converts the 5 bit volume scale into a linear amplitude. Level 0 is silent
+var s5bLevels = func() (levels [s5bSteps]float32) {
+	for i := 1; i < s5bSteps; i++ {
+		levels[i] = float32(math.Pow(10, -s5bStepDB*float64(s5bSteps-1-i)/20))
+	}
+	return
+}()
+
+func newSunsoft5B() sunsoft5B {
+	return sunsoft5B{noise: 1}
+}
+
+// writeAddress selects the register for writeData. Selecting a register above $0F disables writes until the next select
+func (s *sunsoft5B) writeAddress(data uint8) {
+	s.address = data
+}
+
+// writeData writes the selected register
+func (s *sunsoft5B) writeData(data uint8) {
+	if s.address > 0x0F {
+		return
+	}
+	s.registers[s.address] = data
+	if s.address == s5bShape {
+		shape := data & 0x0F
+		s.envelope = s5bEnvelope{attack: shape&s5bAttack != 0}
+		s.envelopeTimer = 0
+	}
+}
+
+// tick is called every CPU cycle
+func (s *sunsoft5B) tick() {
+	if s.divider > 0 {
+		s.divider--
+		return
+	}
+	s.divider = s5bClockDivider - 1
+
+	for i := range s.tones {
+		t := &s.tones[i]
+		t.timer++
+		if t.timer >= s.tonePeriod(i) {
+			t.timer = 0
+			t.output = !t.output
+		}
+	}
+
+	// The noise period is 5 bits. The LFSR shifts half as often as a tone with the same period toggles
+	s.noiseTimer++
+	if s.noiseTimer >= 2*maxUint16(uint16(s.registers[s5bNoisePeriod]&0x1F), 1) {
+		s.noiseTimer = 0
+		bit := (s.noise ^ s.noise>>3) & 0x01
+		s.noise = s.noise>>1 | bit<<16
+	}
+
+	s.envelopeTimer++
+	if s.envelopeTimer >= maxUint16(uint16(s.registers[s5bEnvelopeHigh])<<8|uint16(s.registers[s5bEnvelopeLow]), 1) {
+		s.envelopeTimer = 0
+		s.stepEnvelope()
+	}
+}
+
+// tonePeriod returns the 12 bit period of a channel. A period of 0 behaves like 1
+func (s *sunsoft5B) tonePeriod(channel int) uint16 {
+	r := s.registers[s5bToneLow+channel*2:]
+	return maxUint16(uint16(r[1]&0x0F)<<8|uint16(r[0]), 1)
+}
+
+// stepEnvelope moves the envelope on by one level. At the end of a pass, the envelope drops to 0 and stays there unless
+// the continue bit is set, in which case it holds, changes direction or starts again
+func (s *sunsoft5B) stepEnvelope() {
+	e := &s.envelope
+	if e.holding {
+		return
+	}
+	if e.step < s5bSteps-1 {
+		e.step++
+		return
+	}
+	shape := s.registers[s5bShape]
+	switch {
+	case shape&s5bContinue == 0:
+		e.holding = true
+		e.attack = false
+	case shape&s5bHold != 0:
+		e.holding = true
+		if shape&s5bAlternate != 0 {
+			e.attack = !e.attack
+		}
+	default:
+		if shape&s5bAlternate != 0 {
+			e.attack = !e.attack
+		}
+		e.step = 0
+	}
+}
+
+// level returns the envelope's current level on the 5 bit volume scale
+func (e *s5bEnvelope) level() uint8 {
+	if e.attack {
+		return e.step
+	}
+	return s5bSteps - 1 - e.step
+}
+
+// Sample returns the sum of the three channels, which only ever push the output in one direction
+func (s *sunsoft5B) Sample() float32 {
+	mixer := s.registers[s5bMixer]
+	noise := s.noise&0x01 != 0
+	var sample float32
+	for i, t := range s.tones {
+		toneOff := mixer>>uint(i)&0x01 != 0
+		noiseOff := mixer>>uint(i+3)&0x01 != 0
+		if !(t.output || toneOff) || !(noise || noiseOff) {
+			continue
+		}
+		volume := s.registers[s5bChannelVolume+i]
+		var level uint8
+		if volume&s5bUseEnvelope != 0 {
+			level = s.envelope.level()
+		} else if volume&0x0F != 0 {
+			level = (volume&0x0F)<<1 | 0x01
+		}
+		sample += s5bLevels[level]
+	}
+	return sample / 3
+}
+
+func (s *sunsoft5B) Volume() float32 {
+	return s5bVolume
+}
+
+func maxUint16(a, b uint16) uint16 {
+	if a > b {
+		return a
+	}
+	return b
+}
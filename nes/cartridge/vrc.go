@@ -0,0 +1,84 @@
+package cartridge
+
+// vrcLines describes which CPU address lines a Konami VRC board connects to the chip's two register select inputs.
+// Every board wires them differently, so registers are normalised to $x000-$x003 before being decoded. When the
+// submapper is unknown, the lines of all the boards sharing a mapper number are ORed together, which works because
+// games only ever write with the other lines clear.
+// http://wiki.nesdev.com/w/index.php/VRC2_and_VRC4
+type vrcLines struct {
+	a0 uint16
+	a1 uint16
+}
+
+// register returns the normalised register address, $x000-$x003
+func (l vrcLines) register(address uint16) uint16 {
+	register := address & 0xF000
+	if address&l.a0 != 0 {
+		register |= 0x01
+	}
+	if address&l.a1 != 0 {
+		register |= 0x02
+	}
+	return register
+}
+
+// vrcIRQ is the IRQ counter shared by the VRC4, VRC6 and VRC7. An 8 bit counter counts up from a reload value and
+// raises an IRQ when it overflows. In cycle mode it is clocked every CPU cycle. In scanline mode a prescaler divides
+// the CPU clock by 113.667 to approximate a scanline without watching the PPU.
+// http://wiki.nesdev.com/w/index.php/VRC_IRQ
+type vrcIRQ struct {
+	latch   uint8
+	counter uint8
+	// The prescaler counts down by 3 every CPU cycle from 341, the number of PPU dots in a scanline
+	prescaler int
+
+	enabled bool
+	// The enabled flag to restore when the IRQ is acknowledged
+	enableOnAck bool
+	cycleMode   bool
+}
+
+const (
+	vrcIRQEnableOnAck uint8 = 0x01
+	vrcIRQEnable      uint8 = 0x02
+	vrcIRQCycleMode   uint8 = 0x04
+
+	vrcPrescalerReload = 341
+	vrcPrescalerStep   = 3
+)
+
+// writeControl sets the IRQ mode and enables. Enabling the counter reloads it. The pending IRQ is always acknowledged
+func (i *vrcIRQ) writeControl(data uint8) {
+	i.enableOnAck = data&vrcIRQEnableOnAck != 0
+	i.enabled = data&vrcIRQEnable != 0
+	i.cycleMode = data&vrcIRQCycleMode != 0
+	if i.enabled {
+		i.counter = i.latch
+		i.prescaler = vrcPrescalerReload
+	}
+}
+
+// acknowledge is called when the IRQ is acknowledged, which restores the enable flag saved by the last control write
+func (i *vrcIRQ) acknowledge() {
+	i.enabled = i.enableOnAck
+}
+
+// tick is called every CPU cycle and returns true when the counter overflows and an IRQ should be raised
+func (i *vrcIRQ) tick() bool {
+	if !i.enabled {
+		return false
+	}
+	if !i.cycleMode {
+		i.prescaler -= vrcPrescalerStep
+		if i.prescaler > 0 {
+			return false
+		}
+		i.prescaler += vrcPrescalerReload
+	}
+	if i.counter == 0xFF {
+		i.counter = i.latch
+		return true
+	}
+	i.counter++
+	return false
+}
@@ -0,0 +1,217 @@
+package cartridge
+
+// vrc24 covers the Konami VRC2 and VRC4, mappers 21, 22, 23 and 25. Two switchable 8KB PRG banks, eight 1KB CHR banks
+// and mirroring control. The VRC4 adds a PRG swap mode, single-screen mirroring, wider CHR banks and the VRC IRQ.
+// http://wiki.nesdev.com/w/index.php/VRC2_and_VRC4
+type vrc24 struct {
+	board
+	lines vrcLines
+	vrc2  bool
+	// VRC2a only connects the upper 7 bits of the CHR bank registers, so banks are in 2KB units
+	chrShift uint
+
+	prgBanks [2]int
+	prgSwap  bool
+	chrBanks [8]int
+	irq      vrcIRQ
+
+	// VRC2 boards without PRG RAM have a 1 bit latch at $6000-$6FFF that some games use as a copy protection check
+	latch uint8
+}
+
+const vrcPRGBankSize = 0x2000
+
+// Address lines for each VRC2 and VRC4 board
+var (
+	vrc4a = vrcLines{a0: 0x02, a1: 0x04}
+	vrc4b = vrcLines{a0: 0x02, a1: 0x01}
+	vrc4c = vrcLines{a0: 0x40, a1: 0x80}
+	vrc4d = vrcLines{a0: 0x08, a1: 0x04}
+	vrc4e = vrcLines{a0: 0x04, a1: 0x08}
+	vrc4f = vrcLines{a0: 0x01, a1: 0x02}
+	vrc2a = vrcLines{a0: 0x02, a1: 0x01}
+	vrc2b = vrcLines{a0: 0x01, a1: 0x02}
+	vrc2c = vrcLines{a0: 0x02, a1: 0x01}
+)
+
+// newVRC4Mapper21 returns VRC4a (submapper 1) or VRC4c (submapper 2)
+func newVRC4Mapper21(cart *Cartridge) Mapper {
+	lines := vrcLines{a0: vrc4a.a0 | vrc4c.a0, a1: vrc4a.a1 | vrc4c.a1}
+	if cart.Format == NES20 {
+		switch cart.Submapper {
+		case 1:
+			lines = vrc4a
+		case 2:
+			lines = vrc4c
+		}
+	}
+	return newVRC24(cart, lines, false)
+}
+
+// newVRC2Mapper22 returns VRC2a
+func newVRC2Mapper22(cart *Cartridge) Mapper {
+	m := newVRC24(cart, vrc2a, true)
+	m.chrShift = 1
+	return m
+}
+
+// newVRC24Mapper23 returns VRC4f (submapper 1), VRC4e (submapper 2) or VRC2b (submapper 3)
+func newVRC24Mapper23(cart *Cartridge) Mapper {
+	lines := vrcLines{a0: vrc4f.a0 | vrc4e.a0, a1: vrc4f.a1 | vrc4e.a1}
+	vrc2 := false
+	if cart.Format == NES20 {
+		switch cart.Submapper {
+		case 1:
+			lines = vrc4f
+		case 2:
+			lines = vrc4e
+		case 3:
+			lines, vrc2 = vrc2b, true
+		}
+	}
+	return newVRC24(cart, lines, vrc2)
+}
+
+// newVRC24Mapper25 returns VRC4b (submapper 1), VRC4d (submapper 2) or VRC2c (submapper 3)
+func newVRC24Mapper25(cart *Cartridge) Mapper {
+	lines := vrcLines{a0: vrc4b.a0 | vrc4d.a0, a1: vrc4b.a1 | vrc4d.a1}
+	vrc2 := false
+	if cart.Format == NES20 {
+		switch cart.Submapper {
+		case 1:
+			lines = vrc4b
+		case 2:
+			lines = vrc4d
+		case 3:
+			lines, vrc2 = vrc2c, true
+		}
+	}
+	return newVRC24(cart, lines, vrc2)
+}
+
+func newVRC24(cart *Cartridge, lines vrcLines, vrc2 bool) *vrc24 {
+	return &vrc24{
+		board: newBoard(cart),
+		lines: lines,
+		vrc2:  vrc2,
+	}
+}
+
+// Tick clocks the IRQ counter. The VRC2 has no IRQ
+func (m *vrc24) Tick() {
+	if !m.vrc2 && m.irq.tick() {
+		m.setIRQ(true)
+	}
+}
+
+func (m *vrc24) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= prgROMStart:
+		return m.prg[m.prgOffset(m.prgBankAt(address), vrcPRGBankSize, address)], 0xFF
+	case address >= prgRAMStart:
+		if m.vrc2 && len(m.prgRAM) == 0 {
+			if address < trainerAddress {
+				return m.latch, 0x01
+			}
+			return 0, 0
+		}
+		return m.readPRGRAM(address)
+	}
+	return 0, 0
+}
+
+func (m *vrc24) WriteCPU(address uint16, data uint8) {
+	switch {
+	case address >= prgROMStart:
+		m.writeRegister(m.lines.register(address), data)
+	case address >= prgRAMStart:
+		if m.vrc2 && len(m.prgRAM) == 0 {
+			m.latch = data & 0x01
+			return
+		}
+		m.writePRGRAM(address, data)
+	}
+}
+
+func (m *vrc24) ReadPPU(address uint16) uint8 {
+	if address <= patternTableEnd {
+		return m.chr[m.chrOffset(m.chrBanks[address>>10]>>m.chrShift, 0x0400, address)]
+	}
+	return m.readNametable(address)
+}
+
+func (m *vrc24) WritePPU(address uint16, data uint8) {
+	if address <= patternTableEnd {
+		m.writeCHR(m.chrOffset(m.chrBanks[address>>10]>>m.chrShift, 0x0400, address), data)
+		return
+	}
+	m.writeNametable(address, data)
+}
+
+// prgBankAt returns the 8KB bank mapped at address. The swap mode exchanges $8000 and $C000
+func (m *vrc24) prgBankAt(address uint16) int {
+	switch address & 0xE000 {
+	case 0x8000:
+		if m.prgSwap {
+			return -2
+		}
+		return m.prgBanks[0]
+	case 0xA000:
+		return m.prgBanks[1]
+	case 0xC000:
+		if m.prgSwap {
+			return m.prgBanks[0]
+		}
+		return -2
+	}
+	return -1
+}
+
+func (m *vrc24) writeRegister(register uint16, data uint8) {
+	switch {
+	case register >= 0x8000 && register <= 0x8003:
+		m.prgBanks[0] = int(data & 0x1F)
+	case register == 0x9002 && !m.vrc2:
+		m.prgSwap = data&0x02 != 0
+	case register >= 0x9000 && register <= 0x9003:
+		m.writeMirroring(data)
+	case register >= 0xA000 && register <= 0xA003:
+		m.prgBanks[1] = int(data & 0x1F)
+	case register >= 0xB000 && register <= 0xE003:
+		// Each bank is split into a low nibble at the even register and a high nibble at the odd register
+		bank := int(register-0xB000)>>12<<1 | int(register>>1)&0x01
+		if register&0x01 == 0 {
+			m.chrBanks[bank] = m.chrBanks[bank]&^0x0F | int(data&0x0F)
+		} else {
+			m.chrBanks[bank] = m.chrBanks[bank]&0x0F | int(data&0x1F)<<4
+		}
+	case m.vrc2:
+	case register == 0xF000:
+		m.irq.latch = m.irq.latch&0xF0 | data&0x0F
+	case register == 0xF001:
+		m.irq.latch = m.irq.latch&0x0F | data<<4
+	case register == 0xF002:
+		m.irq.writeControl(data)
+		m.setIRQ(false)
+	case register == 0xF003:
+		m.irq.acknowledge()
+		m.setIRQ(false)
+	}
+}
+
+// writeMirroring selects the mirroring. The VRC2 only has vertical and horizontal
+func (m *vrc24) writeMirroring(data uint8) {
+	if m.vrc2 {
+		data &= 0x01
+	}
+	switch data & 0x03 {
+	case 0:
+		m.mirroring = Vertical
+	case 1:
+		m.mirroring = Horizontal
+	case 2:
+		m.mirroring = SingleScreenLower
+	case 3:
+		m.mirroring = SingleScreenUpper
+	}
+}
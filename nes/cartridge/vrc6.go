@@ -0,0 +1,159 @@
+package cartridge
+
+import "github.com/kevinwmiller/nesgo/nes/apu"
+
+// vrc6 is the Konami VRC6, mappers 24 (VRC6a) and 26 (VRC6b) which swap the A0 and A1 lines. A switchable 16KB and 8KB
+// PRG bank, eight CHR bank registers with several layouts, the VRC IRQ and three extra sound channels.
+// http://wiki.nesdev.com/w/index.php/VRC6
+type vrc6 struct {
+	board
+	lines vrcLines
+
+	prgBank16 int
+	prgBank8  int
+	chrBanks  [8]int
+	// $B003. Bits 0-1 select the CHR layout, bits 2-3 the mirroring, bit 5 whether PPU A10 picks between pairs of 1KB
+	// banks in the 2KB layouts and bit 7 enables PRG RAM
+	control uint8
+	irq     vrcIRQ
+
+	sound vrc6Audio
+}
+
+const (
+	vrc6PRGRAMEnable uint8 = 0x80
+	vrc6CHRA10       uint8 = 0x20
+)
+
+// Address lines for each VRC6 board
+var (
+	vrc6a = vrcLines{a0: 0x01, a1: 0x02}
+	vrc6b = vrcLines{a0: 0x02, a1: 0x01}
+)
+
+func newVRC6a(cart *Cartridge) Mapper {
+	return newVRC6(cart, vrc6a)
+}
+
+func newVRC6b(cart *Cartridge) Mapper {
+	return newVRC6(cart, vrc6b)
+}
+
+func newVRC6(cart *Cartridge, lines vrcLines) *vrc6 {
+	return &vrc6{
+		board: newBoard(cart),
+		lines: lines,
+	}
+}
+
+// Tick clocks the IRQ counter and the sound channels
+func (m *vrc6) Tick() {
+	if m.irq.tick() {
+		m.setIRQ(true)
+	}
+	m.sound.tick()
+}
+
+func (m *vrc6) audio() apu.Expansion {
+	return &m.sound
+}
+
+func (m *vrc6) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= 0xE000:
+		return m.prg[m.prgOffset(-1, vrcPRGBankSize, address)], 0xFF
+	case address >= 0xC000:
+		return m.prg[m.prgOffset(m.prgBank8, vrcPRGBankSize, address)], 0xFF
+	case address >= prgROMStart:
+		return m.prg[m.prgOffset(m.prgBank16, 0x4000, address)], 0xFF
+	case address >= prgRAMStart:
+		if m.control&vrc6PRGRAMEnable == 0 {
+			return 0, 0
+		}
+		return m.readPRGRAM(address)
+	}
+	return 0, 0
+}
+
+func (m *vrc6) WriteCPU(address uint16, data uint8) {
+	switch {
+	case address >= prgROMStart:
+		m.writeRegister(m.lines.register(address), data)
+	case address >= prgRAMStart:
+		if m.control&vrc6PRGRAMEnable != 0 {
+			m.writePRGRAM(address, data)
+		}
+	}
+}
+
+func (m *vrc6) ReadPPU(address uint16) uint8 {
+	if address <= patternTableEnd {
+		return m.chr[m.chrOffset(m.chrBankAt(address), 0x0400, address)]
+	}
+	return m.readNametable(address)
+}
+
+func (m *vrc6) WritePPU(address uint16, data uint8) {
+	if address <= patternTableEnd {
+		m.writeCHR(m.chrOffset(m.chrBankAt(address), 0x0400, address), data)
+		return
+	}
+	m.writeNametable(address, data)
+}
+
+// chrBankAt returns the 1KB CHR bank mapped at address. Layout 0 has eight 1KB banks, layout 1 has four 2KB banks and
+// layouts 2 and 3 have four 1KB banks followed by two 2KB banks. A 2KB bank is either a pair of 1KB banks picked by PPU
+// A10 or the same 1KB bank twice
+func (m *vrc6) chrBankAt(address uint16) int {
+	slot := int(address >> 10)
+	var register int
+	switch m.control & 0x03 {
+	case 0:
+		return m.chrBanks[slot]
+	case 1:
+		register = slot >> 1
+	default:
+		if slot < 4 {
+			return m.chrBanks[slot]
+		}
+		register = 4 + (slot-4)>>1
+	}
+	bank := m.chrBanks[register]
+	if m.control&vrc6CHRA10 != 0 {
+		bank = bank&^1 | slot&0x01
+	}
+	return bank
+}
+
+func (m *vrc6) writeRegister(register uint16, data uint8) {
+	switch {
+	case register >= 0x8000 && register <= 0x8003:
+		m.prgBank16 = int(data & 0x0F)
+	case register == 0xB003:
+		m.control = data
+		switch data & 0x0C {
+		case 0x00:
+			m.mirroring = Vertical
+		case 0x04:
+			m.mirroring = Horizontal
+		case 0x08:
+			m.mirroring = SingleScreenLower
+		case 0x0C:
+			m.mirroring = SingleScreenUpper
+		}
+	case register >= 0x9000 && register <= 0xB002:
+		m.sound.write(register, data)
+	case register >= 0xC000 && register <= 0xC003:
+		m.prgBank8 = int(data & 0x1F)
+	case register >= 0xD000 && register <= 0xE003:
+		m.chrBanks[int(register-0xD000)>>12<<2|int(register&0x03)] = int(data)
+	case register == 0xF000:
+		m.irq.latch = data
+	case register == 0xF001:
+		m.irq.writeControl(data)
+		m.setIRQ(false)
+	case register == 0xF002:
+		m.irq.acknowledge()
+		m.setIRQ(false)
+	}
+}
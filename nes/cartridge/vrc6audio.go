@@ -0,0 +1,137 @@
+package cartridge
+
+// vrc6Audio is the VRC6's sound hardware: two pulse channels with 8 duty cycles and a sawtooth channel.
+// http://wiki.nesdev.com/w/index.php/VRC6_audio
+type vrc6Audio struct {
+	pulse [2]vrc6Pulse
+	saw   vrc6Saw
+
+	// $9003. Halts every channel, or speeds up their timers by 16 or 256 times
+	halt  bool
+	shift uint
+}
+
+type vrc6Pulse struct {
+	volume uint8
+	duty   uint8
+	// Ignore the duty cycle and output the volume constantly. Used for digitised sound
+	constant bool
+	enabled  bool
+	period   uint16
+	timer    uint16
+	step     uint8
+}
+
+type vrc6Saw struct {
+	rate        uint8
+	enabled     bool
+	period      uint16
+	timer       uint16
+	accumulator uint8
+	step        uint8
+}
+
+const (
+	// The largest output, both pulses at volume 15 and the saw accumulator at 255 >> 3
+	vrc6MaxOutput = 15 + 15 + 31
+	vrc6SawSteps  = 14
+	// A VRC6 pulse at full volume is about as loud as an APU pulse at full volume
+	vrc6Volume = apuPulseLevel * vrc6MaxOutput / 15
+)
+
+// write handles a write to one of the audio registers, given as the normalised register address
+func (a *vrc6Audio) write(register uint16, data uint8) {
+	switch register {
+	case 0x9000, 0xA000:
+		p := &a.pulse[(register-0x9000)>>12]
+		p.volume = data & 0x0F
+		p.duty = (data >> 4) & 0x07
+		p.constant = data&0x80 != 0
+	case 0x9001, 0xA001:
+		p := &a.pulse[(register-0x9000)>>12]
+		p.period = p.period&0x0F00 | uint16(data)
+	case 0x9002, 0xA002:
+		p := &a.pulse[(register-0x9000)>>12]
+		p.period = p.period&0x00FF | uint16(data&0x0F)<<8
+		p.enabled = data&0x80 != 0
+		if !p.enabled {
+			p.step = 15
+		}
+	case 0x9003:
+		a.halt = data&0x01 != 0
+		switch {
+		case data&0x04 != 0:
+			a.shift = 8
+		case data&0x02 != 0:
+			a.shift = 4
+		default:
+			a.shift = 0
+		}
+	case 0xB000:
+		a.saw.rate = data & 0x3F
+	case 0xB001:
+		a.saw.period = a.saw.period&0x0F00 | uint16(data)
+	case 0xB002:
+		a.saw.period = a.saw.period&0x00FF | uint16(data&0x0F)<<8
+		a.saw.enabled = data&0x80 != 0
+		if !a.saw.enabled {
+			a.saw.accumulator = 0
+			a.saw.step = 0
+		}
+	}
+}
+
+// tick is called every CPU cycle
+func (a *vrc6Audio) tick() {
+	if a.halt {
+		return
+	}
+	for i := range a.pulse {
+		p := &a.pulse[i]
+		if !p.enabled {
+			continue
+		}
+		if p.timer == 0 {
+			p.timer = p.period >> a.shift
+			p.step = (p.step - 1) & 0x0F
+		} else {
+			p.timer--
+		}
+	}
+
+	s := &a.saw
+	if !s.enabled {
+		return
+	}
+	if s.timer > 0 {
+		s.timer--
+		return
+	}
+	s.timer = s.period >> a.shift
+	// The accumulator is added to on every other clock: 6 additions, reset on the 7th
+	s.step++
+	switch {
+	case s.step == vrc6SawSteps:
+		s.step = 0
+		s.accumulator = 0
+	case s.step&0x01 == 0:
+		s.accumulator += s.rate
+	}
+}
+
+func (a *vrc6Audio) Sample() float32 {
+	var output uint8
+	for _, p := range a.pulse {
+		if p.enabled && (p.constant || p.step <= p.duty) {
+			output += p.volume
+		}
+	}
+	if a.saw.enabled {
+		output += a.saw.accumulator >> 3
+	}
+	return float32(output) / vrc6MaxOutput
+}
+
+func (a *vrc6Audio) Volume() float32 {
+	return vrc6Volume
+}
@@ -0,0 +1,163 @@
+package cartridge
+
+import "github.com/kevinwmiller/nesgo/nes/apu"
+
+// vrc7 is the Konami VRC7, mapper 85. Three switchable 8KB PRG banks, eight 1KB CHR banks, the VRC IRQ and a six
+// channel FM synthesiser derived from the YM2413. VRC7a (submapper 2) selects registers with A4 and VRC7b (submapper 1)
+// with A3.
+// http://wiki.nesdev.com/w/index.php/VRC7
+type vrc7 struct {
+	board
+	// The address line separating the two registers in each 4KB range
+	line uint16
+
+	prgBanks [3]int
+	chrBanks [8]int
+	// $E000. Bits 0-1 select the mirroring, bit 6 silences the sound and resets it, and bit 7 enables PRG RAM
+	control uint8
+	irq     vrcIRQ
+
+	sound opll
+}
+
+const (
+	vrc7aLine uint16 = 0x10
+	vrc7bLine uint16 = 0x08
+
+	vrc7Silence      uint8 = 0x40
+	vrc7PRGRAMEnable uint8 = 0x80
+
+	// The sound registers are written through an address port and a data port
+	vrc7AudioAddress uint16 = 0x9010
+	vrc7AudioData    uint16 = 0x9030
+)
+
+func newVRC7(cart *Cartridge) Mapper {
+	line := vrc7aLine | vrc7bLine
+	if cart.Format == NES20 {
+		switch cart.Submapper {
+		case 1:
+			line = vrc7bLine
+		case 2:
+			line = vrc7aLine
+		}
+	}
+	return &vrc7{
+		board: newBoard(cart),
+		line:  line,
+		sound: newOPLL(),
+	}
+}
+
+// Tick clocks the IRQ counter and the synthesiser
+func (m *vrc7) Tick() {
+	if m.irq.tick() {
+		m.setIRQ(true)
+	}
+	if m.control&vrc7Silence == 0 {
+		m.sound.tick()
+	}
+}
+
+func (m *vrc7) audio() apu.Expansion {
+	return &m.sound
+}
+
+func (m *vrc7) ReadCPU(address uint16) (uint8, uint8) {
+	switch {
+	case address >= 0xE000:
+		return m.prg[m.prgOffset(-1, vrcPRGBankSize, address)], 0xFF
+	case address >= prgROMStart:
+		return m.prg[m.prgOffset(m.prgBanks[(address-prgROMStart)>>13], vrcPRGBankSize, address)], 0xFF
+	case address >= prgRAMStart:
+		if m.control&vrc7PRGRAMEnable == 0 {
+			return 0, 0
+		}
+		return m.readPRGRAM(address)
+	}
+	return 0, 0
+}
+
+func (m *vrc7) WriteCPU(address uint16, data uint8) {
+	switch {
+	case address >= prgROMStart:
+		m.writeRegister(address, data)
+	case address >= prgRAMStart:
+		if m.control&vrc7PRGRAMEnable != 0 {
+			m.writePRGRAM(address, data)
+		}
+	}
+}
+
+func (m *vrc7) ReadPPU(address uint16) uint8 {
+	if address <= patternTableEnd {
+		return m.chr[m.chrOffset(m.chrBanks[address>>10], 0x0400, address)]
+	}
+	return m.readNametable(address)
+}
+
+func (m *vrc7) WritePPU(address uint16, data uint8) {
+	if address <= patternTableEnd {
+		m.writeCHR(m.chrOffset(m.chrBanks[address>>10], 0x0400, address), data)
+		return
+	}
+	m.writeNametable(address, data)
+}
+
+func (m *vrc7) writeRegister(address uint16, data uint8) {
+	// The sound ports are decoded from A4 and A5 on every board
+	switch address & 0xF030 {
+	case vrc7AudioAddress:
+		m.sound.selectRegister(data)
+		return
+	case vrc7AudioData:
+		m.sound.write(data)
+		return
+	}
+
+	odd := address&m.line != 0
+	switch address & 0xF000 {
+	case 0x8000:
+		if odd {
+			m.prgBanks[1] = int(data & 0x3F)
+		} else {
+			m.prgBanks[0] = int(data & 0x3F)
+		}
+	case 0x9000:
+		if !odd {
+			m.prgBanks[2] = int(data & 0x3F)
+		}
+	case 0xA000, 0xB000, 0xC000, 0xD000:
+		bank := int(address-0xA000) >> 12 << 1
+		if odd {
+			bank++
+		}
+		m.chrBanks[bank] = int(data)
+	case 0xE000:
+		if odd {
+			m.irq.latch = data
+			return
+		}
+		if data&vrc7Silence != 0 {
+			m.sound.reset()
+		}
+		m.control = data
+		switch data & 0x03 {
+		case 0:
+			m.mirroring = Vertical
+		case 1:
+			m.mirroring = Horizontal
+		case 2:
+			m.mirroring = SingleScreenLower
+		case 3:
+			m.mirroring = SingleScreenUpper
+		}
+	case 0xF000:
+		if odd {
+			m.irq.acknowledge()
+		} else {
+			m.irq.writeControl(data)
+		}
+		m.setIRQ(false)
+	}
+}
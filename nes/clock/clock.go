@@ -1,19 +1,27 @@
 package clock
 
 import (
-	"bufio"
-	"os"
+	"math"
+	"sync/atomic"
+	"time"
 )
 
-// Clock is a counter that will call registeredComponents on their specified clock cycles
+// Clock is the console's master clock. Components are registered with a divider and tick once every that many master
+// clock cycles, so the ratios between the CPU, PPU and everything else are exact for any region
 type Clock struct {
+	// The master clock frequency in Hz
+	frequency            float64
 	clockCount           uint64
 	registeredComponents []Component
+	// Set by Stop, which may be called from another goroutine
+	stopped int32
 }
 
 // Component represents an object that should be used on a regular interval
 type Component struct {
 	tickOn uint64
+	// The master clock cycle the component ticks on next
+	next   uint64
 	ticker Ticker
 }
 
@@ -22,28 +30,66 @@ type Ticker interface {
 	Tick()
 }
 
-// RegisterComponent registers a ticker that will tick every $tickOn clock cycles
+// New returns a master clock running at frequency Hz
+func New(frequency float64) *Clock {
+	return &Clock{frequency: frequency}
+}
+
+// Frequency returns the master clock frequency in Hz
+func (c *Clock) Frequency() float64 {
+	return c.frequency
+}
+
+// Cycles returns the number of master clock cycles in a duration, at least 1
+func (c *Clock) Cycles(d time.Duration) uint64 {
+	cycles := uint64(d.Seconds() * c.frequency)
+	if cycles == 0 {
+		return 1
+	}
+	return cycles
+}
+
+// RegisterComponent registers a ticker that will tick every $tickOn clock cycles. Components that tick on the same
+// clock cycle tick in the order they were registered
 func (c *Clock) RegisterComponent(ticker Ticker, tickOn uint64) {
 	c.registeredComponents = append(c.registeredComponents, Component{
 		tickOn: tickOn,
+		next:   c.clockCount - c.clockCount%tickOn + tickOn,
 		ticker: ticker,
 	})
 }
 
-// Start starts the clock
+// Start runs the clock until Stop is called
 func (c *Clock) Start() {
-	for {
+	for atomic.LoadInt32(&c.stopped) == 0 {
 		c.Tick()
-		bufio.NewReader(os.Stdin).ReadBytes('\n')
 	}
 }
 
-// Tick goes to the next clock cycle
+// Stop makes Start return after the current clock cycle. It is safe to call from another goroutine
+func (c *Clock) Stop() {
+	atomic.StoreInt32(&c.stopped, 1)
+}
+
+// Tick goes to the next clock cycle that a component ticks on and ticks every component due on it. Clock cycles on
+// which nothing ticks are skipped
 func (c *Clock) Tick() {
-	c.clockCount++
+	next := uint64(math.MaxUint64)
 	for _, component := range c.registeredComponents {
-		if c.clockCount%component.tickOn == 0 {
+		if component.next < next {
+			next = component.next
+		}
+	}
+	if next == math.MaxUint64 {
+		c.clockCount++
+		return
+	}
+	c.clockCount = next
+	for i := range c.registeredComponents {
+		component := &c.registeredComponents[i]
+		if component.next == next {
 			component.ticker.Tick()
+			component.next += component.tickOn
 		}
 	}
 }
@@ -0,0 +1,61 @@
+package clock
+
+import "fmt"
+
+// Region is a console model. Each region has its own master clock frequency and divides it differently for the CPU
+// and PPU. http://wiki.nesdev.com/w/index.php/Cycle_reference_chart
+type Region uint8
+
+// Console regions
+const (
+	NTSC Region = iota
+	PAL
+	// Dendy is the most common Famicom clone in the former Soviet Union. It uses a PAL master clock but keeps NTSC
+	// like CPU and APU timing, with a long post-render period instead of PAL's long vblank
+	Dendy
+)
+
+// regionTiming is the master clock of a region and its divider for the CPU and PPU
+type regionTiming struct {
+	frequency  float64
+	cpuDivider uint64
+	ppuDivider uint64
+}
+
+var regionTimings = [...]regionTiming{
+	NTSC:  {frequency: 236.25e6 / 11, cpuDivider: 12, ppuDivider: 4},
+	PAL:   {frequency: 26601712.5, cpuDivider: 16, ppuDivider: 5},
+	Dendy: {frequency: 26601712.5, cpuDivider: 15, ppuDivider: 5},
+}
+
+func (r Region) String() string {
+	switch r {
+	case NTSC:
+		return "NTSC"
+	case PAL:
+		return "PAL"
+	case Dendy:
+		return "Dendy"
+	}
+	return fmt.Sprintf("Region(%d)", r)
+}
+
+// MasterFrequency returns the frequency of the region's master clock in Hz
+func (r Region) MasterFrequency() float64 {
+	return regionTimings[r].frequency
+}
+
+// CPUDivider returns the number of master clock cycles per CPU cycle
+func (r Region) CPUDivider() uint64 {
+	return regionTimings[r].cpuDivider
+}
+
+// PPUDivider returns the number of master clock cycles per PPU dot
+func (r Region) PPUDivider() uint64 {
+	return regionTimings[r].ppuDivider
+}
+
+// CPUFrequency returns the CPU clock frequency in Hz, which is also the rate the APU runs at
+func (r Region) CPUFrequency() float64 {
+	return r.MasterFrequency() / float64(r.CPUDivider())
+}
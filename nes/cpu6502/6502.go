@@ -1,7 +1,6 @@
 package cpu6502
 
 import (
-	"fmt"
 	"github.com/kevinwmiller/nesgo/nes/bus"
 	"github.com/kevinwmiller/nesgo/nes/cpu6502/flags"
 )
@@ -63,8 +62,38 @@ type CPU struct {
 	cycles          uint8
 	instructions    [256]Instruction
 	addressingModes [14]AddressingMode
-
-	bus *bus.Bus
+	accesses        [256]access
+	// The addressing mode of the instruction currently being executed. Read-modify-write instructions use this to
+	// determine if their result belongs in the accumulator or in memory
+	addressingMode uint8
+	// How the instruction currently being executed accesses its operand
+	access access
+	// Cycles the CPU is halted for after the current instruction while DMA uses the bus
+	stall int
+
+	// Interrupt lines. nmiLine holds the current level of the NMI input while nmiPending latches a detected edge until
+	// it is serviced. irqLine holds one bit for each source currently asserting IRQ
+	nmiLine    bool
+	nmiPending bool
+	irqLine    IRQSource
+	// The status register as it was when interrupts were last polled. CLI, SEI and PLP change the interrupt disable
+	// flag after the poll so their effect on IRQ is delayed by one instruction
+	pollStatus  uint8
+	delayedPoll bool
+	// Set while a BRK or IRQ sequence can still be redirected to the NMI vector
+	hijackable bool
+
+	jamBehavior JamBehavior
+	jammed      bool
+	err         error
+
+	// The total number of cycles executed since the last reset
+	cycleCount uint64
+
+	tracer      *tracer
+	ppuPosition PPUPosition
+
+	bus bus.Device
 }
 
 // NewCPU returns a new CPU object with all flags initialized.
@@ -73,6 +102,7 @@ func NewCPU() *CPU {
 	// The unused flag should be set at all times
 	cpu.instructions = cpu.buildInstructionTable()
 	cpu.addressingModes = cpu.buildAddressingModeTable()
+	cpu.accesses = buildAccessTable(&cpu.instructions)
 	return &cpu
 }
 
@@ -92,14 +122,16 @@ func (c *CPU) Read(address uint16) uint8 {
 
 // Reset resets the CPU to a known state.
 // The program counter starting address is loaded from 0xFFFC and 0xFFFD
-// The registers and flags (with the exception of the unused flag) are initialized to 0
+// The registers and flags (with the exception of the unused and interrupt disable flags) are initialized to 0
 func (c *CPU) Reset() {
 	// The initial state of the program counter can be found at 0xFFFC and 0xFFFD
-	addr := uint16(0xFFFC)
-	lowByte := c.Read(addr)
-	hiByte := c.Read(addr + 1)
-	c.PC = buildAddress(hiByte, lowByte)
-	c.Status = flags.SetFlag(0x00, flags.U)
+	c.PC = c.readAddress(resetVector)
+	c.Status = flags.SetFlag(0x00, flags.U|flags.I)
+	c.pollStatus = c.Status
+	c.nmiPending = false
+	c.hijackable = false
+	c.jammed = false
+	c.err = nil
 	c.A = 0
 	c.X = 0
 	c.Y = 0
@@ -107,26 +139,63 @@ func (c *CPU) Reset() {
 	// http://wiki.nesdev.com/w/index.php/CPU_power_up_state
 	c.SP = 0xFD
 
-	c.cycles = 6
+	// The reset sequence takes 7 cycles before the first instruction is fetched
+	c.cycles = 7
+	c.stall = 0
+	c.cycleCount = 0
 }
 
 // Tick executes a single fetch/decode/execute cycle
 func (c *CPU) Tick() {
-	fmt.Println("Ticking")
-	if c.cycles == 0 {
-		c.Dump()
-		opcode := c.fetch()
-		instruction := &c.instructions[opcode]
-		addressingMode := &c.addressingModes[instruction.AddressingMode]
-		c.cycles = instruction.Cycles
-
-		data, address, pageBoundaryCrossed := addressingMode.Lookup()
+	if c.jammed {
+		return
+	}
+	if c.cycles == 0 && c.stall > 0 {
+		c.stall--
+		c.cycleCount++
+		return
+	}
+	if c.cycles == 0 && !c.pollInterrupts() {
+		c.execute()
+	}
+	c.cycles--
+	c.cycleCount++
+	c.hijack()
+}
+
+// execute fetches, decodes and executes the instruction at the program counter and sets the number of cycles it takes
+func (c *CPU) execute() {
+	c.hijackable = false
+	if c.tracer != nil {
+		c.trace()
+	}
+	opcode := c.fetch()
+	instruction := &c.instructions[opcode]
+	addressingMode := &c.addressingModes[instruction.AddressingMode]
+	c.addressingMode = instruction.AddressingMode
+	c.access = c.accesses[opcode]
+	c.cycles = instruction.Cycles
+
+	var data uint8
+	var address uint16
+	if c.access != selfAccess {
+		var pageBoundaryCrossed bool
+		data, address, pageBoundaryCrossed = addressingMode.Lookup()
 		if pageBoundaryCrossed {
 			c.cycles += instruction.AdditionalCycles
 		}
-		c.cycles += instruction.Execute(data, address)
 	}
-	c.cycles--
+	c.cycles += instruction.Execute(data, address)
+
+	if !c.delayedPoll {
+		c.pollStatus = c.Status
+	}
+	c.delayedPoll = false
+}
+
+// Cycles returns the number of cycles executed since the last reset
+func (c *CPU) Cycles() uint64 {
+	return c.cycleCount
 }
 
 // fetch fetches the next instruction
@@ -140,29 +209,27 @@ func (c *CPU) fetch() uint8 {
 // 	return &instructions[]
 // }
 
-// ConnectBus connects a bus to the CPU
-func (c *CPU) ConnectBus(bus *bus.Bus) {
-	c.bus = bus
+// Step runs the CPU until the instruction or interrupt sequence in progress has completed, then executes the next
+// instruction, or services a pending interrupt, to completion. It returns the number of cycles the step took
+func (c *CPU) Step() int {
+	for c.busy() {
+		c.Tick()
+	}
+	start := c.cycleCount
+	c.Tick()
+	for c.busy() {
+		c.Tick()
+	}
+	return int(c.cycleCount - start)
 }
 
-// Dump prints the CPU state to the console
-func (c *CPU) Dump() {
-	fmt.Printf("PC: %X\n", c.PC)
-	fmt.Printf("A : %X\n", c.A)
-	fmt.Printf("X : %X\n", c.X)
-	fmt.Printf("Y : %X\n", c.Y)
-	fmt.Printf("SP: %X\n", c.SP)
-	fmt.Printf("Status: %08b\n", c.Status)
-	fmt.Printf("Cycles: %d\n", c.cycles)
-
-	// fmt.Println("Instructions: ")
-	// for opcode, instruction := range c.instructions {
-	// 	fmt.Printf("    %02X: %s - %-12s - %d cycles +%d*\n",
-	// 		opcode,
-	// 		instruction.Name,
-	// 		c.addressingModes[instruction.AddressingMode].Name,
-	// 		instruction.Cycles,
-	// 		instruction.AdditionalCycles,
-	// 	)
-	// }
+// busy returns true while an instruction, interrupt sequence or DMA stall is in progress
+func (c *CPU) busy() bool {
+	return (c.cycles > 0 || c.stall > 0) && !c.jammed
+}
+
+// ConnectBus connects a bus to the CPU. Usually this is a *bus.Bus but any device can stand in for the whole address
+// space
+func (c *CPU) ConnectBus(bus bus.Device) {
+	c.bus = bus
 }
@@ -0,0 +1,53 @@
+package cpu6502
+
+// access describes what an instruction does with the memory at its operand address. The addressing modes use it to
+// make the same reads and writes the hardware makes
+type access uint8
+
+const (
+	// readAccess instructions read their operand
+	readAccess access = iota
+	// writeAccess instructions only write to the operand address
+	writeAccess
+	// modifyAccess instructions read the operand, write it back unmodified and then write the result
+	modifyAccess
+	// addressAccess instructions only need the operand address and never access it
+	addressAccess
+	// selfAccess instructions fetch their own operand because they touch the stack part way through reading it
+	selfAccess
+)
+
+// The operand access of each mnemonic. Anything not listed reads its operand
+var instructionAccess = map[string]access{
+	"STA": writeAccess,
+	"STX": writeAccess,
+	"STY": writeAccess,
+	"SAX": writeAccess,
+	"SHA": writeAccess,
+	"SHX": writeAccess,
+	"SHY": writeAccess,
+	"TAS": writeAccess,
+	"ASL": modifyAccess,
+	"LSR": modifyAccess,
+	"ROL": modifyAccess,
+	"ROR": modifyAccess,
+	"INC": modifyAccess,
+	"DEC": modifyAccess,
+	"SLO": modifyAccess,
+	"RLA": modifyAccess,
+	"SRE": modifyAccess,
+	"RRA": modifyAccess,
+	"DCP": modifyAccess,
+	"ISB": modifyAccess,
+	"JMP": addressAccess,
+	"JSR": selfAccess,
+}
+
+// buildAccessTable returns the operand access for every opcode
+func buildAccessTable(instructions *[256]Instruction) [256]access {
+	var accesses [256]access
+	for opcode, instruction := range instructions {
+		accesses[opcode] = instructionAccess[instruction.Name]
+	}
+	return accesses
+}
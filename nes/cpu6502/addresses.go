@@ -1,9 +1,23 @@
 package cpu6502
 
+// Interrupt vectors. Each vector holds the little endian address the CPU jumps to when the interrupt occurs
+const (
+	nmiVector   uint16 = 0xFFFA
+	resetVector uint16 = 0xFFFC
+	irqVector   uint16 = 0xFFFE
+)
+
 func buildAddress(hiByte, lowByte uint8) uint16 {
 	return uint16(hiByte)<<8 | uint16(lowByte)
 }
 
+// readAddress reads a little endian 16 bit address starting at address
+func (c *CPU) readAddress(address uint16) uint16 {
+	lowByte := c.Read(address)
+	hiByte := c.Read(address + 1)
+	return buildAddress(hiByte, lowByte)
+}
+
 // SetProgramCounter sets the CPU program counter given two bytes that make up an address
 func (c *CPU) SetProgramCounter(hiByte, lowByte uint8) {
 	c.PC = buildAddress(hiByte, lowByte)
@@ -1,6 +1,9 @@
 package cpu6502
 
-// An AddressingMode handles how the data for the current operation is fetched
+// An AddressingMode handles how the data for the current operation is fetched.
+// Lookup performs every bus access the 6502 makes while resolving the operand, including the dummy reads made while
+// the high byte of an indexed address is being fixed up, so that devices with read side effects see the same
+// sequence of accesses as on the hardware
 type AddressingMode struct {
 	Name   string
 	Lookup func() (uint8, uint16, bool)
@@ -62,11 +65,13 @@ func (c *CPU) und() (uint8, uint16, bool) {
 }
 
 // Accumulator
+// Like implied instructions, the CPU reads the byte after the opcode and throws it away
 func (c *CPU) acc() (uint8, uint16, bool) {
+	c.Read(c.PC)
 	return c.A, 0, false
 }
 
-// Absolute
+// Immediate
 func (c *CPU) imm() (uint8, uint16, bool) {
 	addr := c.PC
 	data := c.Read(addr)
@@ -78,23 +83,29 @@ func (c *CPU) imm() (uint8, uint16, bool) {
 func (c *CPU) zp0() (uint8, uint16, bool) {
 	zpAddr := c.Read(c.PC)
 	c.PC++
-	data := c.Read(uint16(zpAddr))
+	data := c.readOperand(uint16(zpAddr))
 	return data, uint16(zpAddr), false
 }
 
 // Zero-page X
+// The base address is read again while X is added to it
 func (c *CPU) zpx() (uint8, uint16, bool) {
-	zpAddr := c.Read(c.PC) + c.X
+	base := c.Read(c.PC)
 	c.PC++
-	data := c.Read(uint16(zpAddr))
+	c.Read(uint16(base))
+	zpAddr := base + c.X
+	data := c.readOperand(uint16(zpAddr))
 	return data, uint16(zpAddr), false
 }
 
 // Zero-page Y
+// The base address is read again while Y is added to it
 func (c *CPU) zpy() (uint8, uint16, bool) {
-	zpAddr := c.Read(c.PC) + c.Y
+	base := c.Read(c.PC)
 	c.PC++
-	data := c.Read(uint16(zpAddr))
+	c.Read(uint16(base))
+	zpAddr := base + c.Y
+	data := c.readOperand(uint16(zpAddr))
 	return data, uint16(zpAddr), false
 }
 
@@ -105,7 +116,7 @@ func (c *CPU) abs() (uint8, uint16, bool) {
 	hiByte := c.Read(c.PC)
 	c.PC++
 	addr := buildAddress(hiByte, lowByte)
-	data := c.Read(addr)
+	data := c.readOperand(addr)
 	return data, addr, false
 }
 
@@ -115,11 +126,7 @@ func (c *CPU) abx() (uint8, uint16, bool) {
 	c.PC++
 	hiByte := c.Read(c.PC)
 	c.PC++
-	addr := buildAddress(hiByte, lowByte)
-	addr += uint16(c.X)
-	data := c.Read(addr)
-	pageBoundaryCrossed := addr&0xFF00 != (uint16(hiByte) << 8)
-	return data, addr, pageBoundaryCrossed
+	return c.indexed(buildAddress(hiByte, lowByte), c.X)
 }
 
 // Absolute Y
@@ -128,24 +135,23 @@ func (c *CPU) aby() (uint8, uint16, bool) {
 	c.PC++
 	hiByte := c.Read(c.PC)
 	c.PC++
-	addr := buildAddress(hiByte, lowByte)
-	addr += uint16(c.Y)
-	data := c.Read(addr)
-	pageBoundaryCrossed := addr&0xFF00 != (uint16(hiByte) << 8)
-	return data, addr, pageBoundaryCrossed
+	return c.indexed(buildAddress(hiByte, lowByte), c.Y)
 }
 
 // Implied
+// The CPU always reads the byte after the opcode, even though it is not used
 func (c *CPU) imp() (uint8, uint16, bool) {
+	c.Read(c.PC)
 	return 0, 0, false
 }
 
 // Relative
+// The operand is a signed offset from the address of the next instruction. The returned address is the branch target
 func (c *CPU) rel() (uint8, uint16, bool) {
-	addr := c.PC
+	offset := c.Read(c.PC)
 	c.PC++
-	data := c.Read(addr)
-	return data, addr, false
+	addr := c.PC + uint16(int8(offset))
+	return offset, addr, false
 }
 
 // Pre-Increment Indirect Addressing
@@ -154,13 +160,16 @@ func (c *CPU) rel() (uint8, uint16, bool) {
 //   In this mode a zer0-page address is added to the contents of the X-register
 //   to give the address of the bytes holding the address of the operand
 func (c *CPU) izx() (uint8, uint16, bool) {
-	ptr := c.PC
+	base := c.Read(c.PC)
 	c.PC++
-	lowByte := c.Read(ptr + uint16(c.X))
-	hiByte := c.Read(ptr + uint16(c.X) + 1)
+	c.Read(uint16(base))
+	ptr := base + c.X
+	// The pointer never leaves the zero page
+	lowByte := c.Read(uint16(ptr))
+	hiByte := c.Read(uint16(ptr + 1))
 
 	addr := buildAddress(hiByte, lowByte)
-	data := c.Read(addr)
+	data := c.readOperand(addr)
 	return data, addr, false
 }
 
@@ -170,15 +179,11 @@ func (c *CPU) izx() (uint8, uint16, bool) {
 //   give the indirect address which is added to the contents of the Y-register
 //   to yield the actual address of the operand.
 func (c *CPU) izy() (uint8, uint16, bool) {
-	ptr := c.PC
+	ptr := c.Read(c.PC)
 	c.PC++
-	lowByte := c.Read(ptr)
-	hiByte := c.Read(ptr + 1)
-
-	addr := buildAddress(hiByte, lowByte) + uint16(c.Y)
-	data := c.Read(addr)
-	pageBoundaryCrossed := addr&0xFF00 != (uint16(hiByte) << 8)
-	return data, addr, pageBoundaryCrossed
+	lowByte := c.Read(uint16(ptr))
+	hiByte := c.Read(uint16(ptr + 1))
+	return c.indexed(buildAddress(hiByte, lowByte), c.Y)
 }
 
 // Indirect
@@ -194,12 +199,41 @@ func (c *CPU) ind() (uint8, uint16, bool) {
 	var hiByte uint8
 	// There is a hardware bug that causes the location of the high byte to wrap around to the beginning of the current page instead of
 	// current page if the low byte is on a page boundary. Need to simulate this bug
-	if lowByte == 0x00FF {
+	if ptrLowByte == 0xFF {
 		hiByte = c.Read(ptr & 0xFF00)
 	} else {
 		hiByte = c.Read(ptr + 1)
 	}
 	addr := buildAddress(hiByte, lowByte)
-	data := c.Read(addr)
+	data := c.readOperand(addr)
 	return data, addr, false
 }
+
+// indexed adds index to base and reads the operand.
+// The CPU adds the index to the low byte first and reads from the resulting address before the high byte has been
+// fixed up. Read instructions only pay for that read if a page boundary was crossed, since otherwise it already was
+// the right address. Write and read-modify-write instructions always make the extra read
+func (c *CPU) indexed(base uint16, index uint8) (uint8, uint16, bool) {
+	addr := base + uint16(index)
+	pageBoundaryCrossed := addr&0xFF00 != base&0xFF00
+	if pageBoundaryCrossed || c.access != readAccess {
+		c.Read(base&0xFF00 | addr&0x00FF)
+	}
+	data := c.readOperand(addr)
+	return data, addr, pageBoundaryCrossed
+}
+
+// readOperand reads the operand for the current instruction from address.
+// Write-only instructions never read their operand. Read-modify-write instructions write the unmodified value back
+// while the new value is being calculated, one cycle before the result is written
+func (c *CPU) readOperand(address uint16) uint8 {
+	switch c.access {
+	case readAccess:
+		return c.Read(address)
+	case modifyAccess:
+		data := c.Read(address)
+		c.Write(address, data)
+		return data
+	}
+	return 0
+}
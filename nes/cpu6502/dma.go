@@ -0,0 +1,53 @@
+package cpu6502
+
+import "github.com/kevinwmiller/nesgo/nes/bus"
+
+// OAM DMA copies a page of CPU memory to the PPU's OAMDATA register, one read and one write per byte.
+// http://wiki.nesdev.com/w/index.php/PPU_registers#OAMDMA
+const (
+	oamDataAddress uint16 = 0x2004
+	oamDMABytes           = 256
+	// A halt cycle plus a read and write for every byte. One more alignment cycle is needed if the DMA starts on an odd
+	// cycle
+	oamDMACycles = 1 + 2*oamDMABytes
+)
+
+// Stall halts the CPU for cycles cycles once the instruction in progress has completed, as happens while the DMA
+// units use the bus
+func (c *CPU) Stall(cycles int) {
+	c.stall += cycles
+}
+
+// oamDMA is the 2A03's sprite DMA unit
+type oamDMA struct {
+	cpu *CPU
+}
+
+// OAMDMA returns the sprite DMA register, which belongs at bus.OAMDMA. Writing a page number to it copies $XX00-$XXFF
+// to the PPU and stalls the CPU for 513 or 514 cycles
+func (c *CPU) OAMDMA() bus.PartialDevice {
+	return oamDMA{cpu: c}
+}
+
+// Read returns open bus. The register is write only
+func (d oamDMA) Read(address uint16) uint8 {
+	return 0
+}
+
+// ReadPartial drives none of the data lines
+func (d oamDMA) ReadPartial(address uint16) (data, driven uint8) {
+	return 0, 0
+}
+
+func (d oamDMA) Write(address uint16, page uint8) {
+	c := d.cpu
+	for i := uint16(0); i < oamDMABytes; i++ {
+		c.Write(oamDataAddress, c.Read(uint16(page)<<8|i))
+	}
+	// The write to $4014 is the last cycle of the instruction making it and the DMA starts on the cycle after
+	cycles := oamDMACycles
+	if (c.cycleCount+uint64(c.cycles))%2 == 1 {
+		cycles++
+	}
+	c.Stall(cycles)
+}
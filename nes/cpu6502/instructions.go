@@ -1,5 +1,7 @@
 package cpu6502
 
+import "github.com/kevinwmiller/nesgo/nes/cpu6502/flags"
+
 // Instruction represents a single 6502 instruction.
 // Every instruction has a name, an implementation, an addressing mode, and a set number of cycle to run
 type Instruction struct {
@@ -9,290 +11,513 @@ type Instruction struct {
 	Cycles         uint8
 	// The number of cycles to add if a page boundary is crossed
 	AdditionalCycles uint8
+	// Unofficial instructions are not documented by MOS but are a side effect of how the opcode decoding logic works.
+	// They behave consistently enough that some commercial games and many test ROMs rely on them
+	Unofficial bool
 }
 
 func (c *CPU) buildInstructionTable() [256]Instruction {
 	return [...]Instruction{
-		{"BRK", c.brk, Rel, 7, 0}, {"ORA", c.ora, Izx, 6, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"ORA", c.ora, Zp0, 3, 0}, {"ASL", c.asl, Zp0, 5, 0}, {"FUT", c.fut, Und, 0, 0}, {"PHP", c.php, Imp, 3, 0}, {"ORA", c.ora, Imm, 2, 0},
-		{"ASL", c.asl, Acc, 2, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"ORA", c.ora, Abs, 4, 0}, {"ASL", c.asl, Abs, 6, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"BPL", c.bpl, Rel, 2, 1}, {"ORA", c.ora, Izy, 5, 1}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"ORA", c.ora, Zpx, 4, 0}, {"ASL", c.asl, Zpx, 6, 0}, {"FUT", c.fut, Und, 0, 0}, {"CLC", c.clc, Imp, 2, 0},
-		{"ORA", c.ora, Aby, 4, 1}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"ORA", c.ora, Abx, 4, 1},
-		{"ASL", c.asl, Abx, 7, 0}, {"FUT", c.fut, Und, 0, 0}, {"JSR", c.jsr, Abs, 6, 0}, {"AND", c.and, Izx, 6, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"BIT", c.bit, Zp0, 3, 0}, {"AND", c.and, Zp0, 3, 0}, {"ROL", c.rol, Zp0, 5, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"PLP", c.plp, Imp, 4, 0}, {"AND", c.and, Imm, 2, 0}, {"ROL", c.rol, Acc, 2, 0}, {"FUT", c.fut, Und, 0, 0}, {"BIT", c.bit, Abs, 4, 0},
-		{"AND", c.and, Abs, 4, 0}, {"ROL", c.rol, Abs, 6, 0}, {"FUT", c.fut, Und, 0, 0}, {"BMI", c.bmi, Rel, 2, 1}, {"AND", c.and, Izy, 5, 1},
-		{"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"AND", c.and, Zpx, 4, 0}, {"ROL", c.rol, Zpx, 6, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"SEC", c.sec, Imp, 2, 0}, {"AND", c.and, Aby, 4, 1}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"AND", c.and, Abx, 4, 1}, {"ROL", c.rol, Abx, 7, 0}, {"FUT", c.fut, Und, 0, 0}, {"RTI", c.rti, Imp, 6, 0},
-		{"EOR", c.eor, Izx, 6, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"EOR", c.eor, Zp0, 3, 0},
-		{"LSR", c.lsr, Zp0, 5, 0}, {"FUT", c.fut, Und, 0, 0}, {"PHA", c.pha, Imp, 3, 0}, {"EOR", c.eor, Imm, 2, 0}, {"LSR", c.lsr, Acc, 2, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"JMP", c.jmp, Abs, 3, 0}, {"EOR", c.eor, Abs, 4, 0}, {"LSR", c.lsr, Abs, 6, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"BVC", c.bvc, Rel, 2, 1}, {"EOR", c.eor, Izy, 5, 1}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"EOR", c.eor, Zpx, 4, 0}, {"LSR", c.lsr, Zpx, 6, 0}, {"FUT", c.fut, Und, 0, 0}, {"CLI", c.cli, Imp, 2, 0}, {"EOR", c.eor, Aby, 4, 1},
-		{"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"EOR", c.eor, Abx, 4, 1}, {"LSR", c.lsr, Abx, 7, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"RTS", c.rts, Imp, 6, 0}, {"ADC", c.adc, Izx, 6, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"ADC", c.adc, Zp0, 3, 0}, {"ROR", c.ror, Zp0, 5, 0}, {"FUT", c.fut, Und, 0, 0}, {"PLA", c.pla, Imp, 4, 0},
-		{"ADC", c.adc, Imm, 2, 0}, {"ROR", c.ror, Acc, 2, 0}, {"FUT", c.fut, Und, 0, 0}, {"JMP", c.jmp, Ind, 5, 0}, {"ADC", c.adc, Abs, 4, 0},
-		{"ROR", c.ror, Abs, 6, 0}, {"FUT", c.fut, Und, 0, 0}, {"BVS", c.bvs, Rel, 2, 1}, {"ADC", c.adc, Izy, 5, 1}, {"FUT", c.fut, Und, 0, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"ADC", c.adc, Zpx, 4, 0}, {"ROR", c.ror, Zpx, 6, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"SEI", c.sei, Imp, 2, 0}, {"ADC", c.adc, Aby, 4, 1}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"ADC", c.adc, Abx, 4, 1}, {"ROR", c.ror, Abx, 7, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"STA", c.sta, Izx, 6, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"STY", c.sty, Zp0, 3, 0}, {"STA", c.sta, Zp0, 3, 0}, {"STX", c.stx, Zp0, 3, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"DEY", c.dey, Imp, 2, 0}, {"FUT", c.fut, Und, 0, 0}, {"TXA", c.txa, Imp, 2, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"STY", c.sty, Abs, 4, 0}, {"STA", c.sta, Abs, 4, 0}, {"STX", c.stx, Abs, 4, 0}, {"FUT", c.fut, Und, 0, 0}, {"BCC", c.bcc, Rel, 2, 1},
-		{"STA", c.sta, Izy, 6, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"STY", c.sty, Zpx, 4, 0}, {"STA", c.sta, Zpx, 4, 0},
-		{"STX", c.stx, Zpy, 4, 0}, {"FUT", c.fut, Und, 0, 0}, {"TYA", c.tya, Imp, 2, 0}, {"STA", c.sta, Aby, 5, 0}, {"TXS", c.txs, Imp, 2, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"STA", c.sta, Abx, 5, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"LDY", c.ldy, Imm, 2, 0}, {"LDA", c.lda, Izx, 6, 0}, {"LDX", c.ldx, Imm, 2, 0}, {"FUT", c.fut, Und, 0, 0}, {"LDY", c.ldy, Zp0, 3, 0},
-		{"LDA", c.lda, Zp0, 3, 0}, {"LDX", c.ldx, Zp0, 3, 0}, {"FUT", c.fut, Und, 0, 0}, {"TAY", c.tay, Imp, 2, 0}, {"LDA", c.lda, Imm, 2, 0},
-		{"TAX", c.tax, Imp, 2, 0}, {"FUT", c.fut, Und, 0, 0}, {"LDY", c.ldy, Abs, 4, 0}, {"LDA", c.lda, Abs, 4, 0}, {"LDX", c.ldx, Abs, 4, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"BCS", c.bcs, Rel, 2, 1}, {"LDA", c.lda, Izy, 5, 1}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"LDY", c.ldy, Zpx, 4, 0}, {"LDA", c.lda, Zpx, 4, 0}, {"LDX", c.ldx, Zpy, 4, 0}, {"FUT", c.fut, Und, 0, 0}, {"CLV", c.clv, Imp, 2, 0},
-		{"LDA", c.lda, Aby, 4, 1}, {"TSX", c.tsx, Imp, 2, 0}, {"FUT", c.fut, Und, 0, 0}, {"LDY", c.ldy, Abx, 4, 1}, {"LDA", c.lda, Abx, 4, 1},
-		{"LDX", c.ldx, Aby, 4, 1}, {"FUT", c.fut, Und, 0, 0}, {"Cpy", c.cpy, Imm, 2, 0}, {"CMP", c.cmp, Izx, 6, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"CPY", c.cpy, Zp0, 3, 0}, {"CMP", c.cmp, Zp0, 3, 0}, {"DEC", c.dec, Zp0, 5, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"INY", c.iny, Imp, 2, 0}, {"CMP", c.cmp, Imm, 2, 0}, {"DEX", c.dex, Imp, 2, 0}, {"FUT", c.fut, Und, 0, 0}, {"CPY", c.cpy, Abs, 4, 0},
-		{"CMP", c.cmp, Abs, 4, 0}, {"DEC", c.dec, Abs, 6, 0}, {"FUT", c.fut, Und, 0, 0}, {"BNE", c.bne, Rel, 2, 1}, {"CMP", c.cmp, Izy, 5, 1},
-		{"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"CMP", c.cmp, Zpx, 4, 0}, {"DEC", c.dec, Zpx, 6, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"CLD", c.cld, Imp, 2, 0}, {"CMP", c.cmp, Aby, 4, 1}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"CMP", c.cmp, Abx, 4, 1}, {"DEC", c.dec, Abx, 7, 0}, {"FUT", c.fut, Und, 0, 0}, {"CPX", c.cpx, Imm, 2, 0},
-		{"SBC", c.sbc, Izx, 6, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"CPX", c.cpx, Zp0, 3, 0}, {"SBC", c.sbc, Zp0, 3, 0},
-		{"INC", c.inc, Zp0, 5, 0}, {"FUT", c.fut, Und, 0, 0}, {"INX", c.inx, Imp, 2, 0}, {"SBC", c.sbc, Imm, 2, 0}, {"NOP", c.nop, Imp, 2, 0},
-		{"FUT", c.fut, Und, 0, 0}, {"CPX", c.cpx, Abs, 4, 0}, {"SBC", c.sbc, Abs, 4, 0}, {"INC", c.inc, Abs, 6, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"BEQ", c.beq, Rel, 2, 1}, {"SBC", c.sbc, Izy, 5, 1}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0},
-		{"SBC", c.sbc, Zpx, 4, 0}, {"INC", c.inc, Zpx, 6, 0}, {"FUT", c.fut, Und, 0, 0}, {"SED", c.sed, Imp, 2, 0}, {"SBC", c.sbc, Aby, 4, 1},
-		{"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"FUT", c.fut, Und, 0, 0}, {"SBC", c.sbc, Abx, 4, 1}, {"INC", c.inc, Abx, 7, 0},
-		{"FUT", c.fut, Und, 0, 0},
+		{"BRK", c.brk, Imp, 7, 0, false}, {"ORA", c.ora, Izx, 6, 0, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"SLO", c.slo, Izx, 8, 0, true},
+		{"NOP", c.nop, Zp0, 3, 0, true}, {"ORA", c.ora, Zp0, 3, 0, false}, {"ASL", c.asl, Zp0, 5, 0, false}, {"SLO", c.slo, Zp0, 5, 0, true},
+		{"PHP", c.php, Imp, 3, 0, false}, {"ORA", c.ora, Imm, 2, 0, false}, {"ASL", c.asl, Acc, 2, 0, false}, {"ANC", c.anc, Imm, 2, 0, true},
+		{"NOP", c.nop, Abs, 4, 0, true}, {"ORA", c.ora, Abs, 4, 0, false}, {"ASL", c.asl, Abs, 6, 0, false}, {"SLO", c.slo, Abs, 6, 0, true},
+		{"BPL", c.bpl, Rel, 2, 1, false}, {"ORA", c.ora, Izy, 5, 1, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"SLO", c.slo, Izy, 8, 0, true},
+		{"NOP", c.nop, Zpx, 4, 0, true}, {"ORA", c.ora, Zpx, 4, 0, false}, {"ASL", c.asl, Zpx, 6, 0, false}, {"SLO", c.slo, Zpx, 6, 0, true},
+		{"CLC", c.clc, Imp, 2, 0, false}, {"ORA", c.ora, Aby, 4, 1, false}, {"NOP", c.nop, Imp, 2, 0, true}, {"SLO", c.slo, Aby, 7, 0, true},
+		{"NOP", c.nop, Abx, 4, 1, true}, {"ORA", c.ora, Abx, 4, 1, false}, {"ASL", c.asl, Abx, 7, 0, false}, {"SLO", c.slo, Abx, 7, 0, true},
+		{"JSR", c.jsr, Abs, 6, 0, false}, {"AND", c.and, Izx, 6, 0, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"RLA", c.rla, Izx, 8, 0, true},
+		{"BIT", c.bit, Zp0, 3, 0, false}, {"AND", c.and, Zp0, 3, 0, false}, {"ROL", c.rol, Zp0, 5, 0, false}, {"RLA", c.rla, Zp0, 5, 0, true},
+		{"PLP", c.plp, Imp, 4, 0, false}, {"AND", c.and, Imm, 2, 0, false}, {"ROL", c.rol, Acc, 2, 0, false}, {"ANC", c.anc, Imm, 2, 0, true},
+		{"BIT", c.bit, Abs, 4, 0, false}, {"AND", c.and, Abs, 4, 0, false}, {"ROL", c.rol, Abs, 6, 0, false}, {"RLA", c.rla, Abs, 6, 0, true},
+		{"BMI", c.bmi, Rel, 2, 1, false}, {"AND", c.and, Izy, 5, 1, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"RLA", c.rla, Izy, 8, 0, true},
+		{"NOP", c.nop, Zpx, 4, 0, true}, {"AND", c.and, Zpx, 4, 0, false}, {"ROL", c.rol, Zpx, 6, 0, false}, {"RLA", c.rla, Zpx, 6, 0, true},
+		{"SEC", c.sec, Imp, 2, 0, false}, {"AND", c.and, Aby, 4, 1, false}, {"NOP", c.nop, Imp, 2, 0, true}, {"RLA", c.rla, Aby, 7, 0, true},
+		{"NOP", c.nop, Abx, 4, 1, true}, {"AND", c.and, Abx, 4, 1, false}, {"ROL", c.rol, Abx, 7, 0, false}, {"RLA", c.rla, Abx, 7, 0, true},
+		{"RTI", c.rti, Imp, 6, 0, false}, {"EOR", c.eor, Izx, 6, 0, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"SRE", c.sre, Izx, 8, 0, true},
+		{"NOP", c.nop, Zp0, 3, 0, true}, {"EOR", c.eor, Zp0, 3, 0, false}, {"LSR", c.lsr, Zp0, 5, 0, false}, {"SRE", c.sre, Zp0, 5, 0, true},
+		{"PHA", c.pha, Imp, 3, 0, false}, {"EOR", c.eor, Imm, 2, 0, false}, {"LSR", c.lsr, Acc, 2, 0, false}, {"ALR", c.alr, Imm, 2, 0, true},
+		{"JMP", c.jmp, Abs, 3, 0, false}, {"EOR", c.eor, Abs, 4, 0, false}, {"LSR", c.lsr, Abs, 6, 0, false}, {"SRE", c.sre, Abs, 6, 0, true},
+		{"BVC", c.bvc, Rel, 2, 1, false}, {"EOR", c.eor, Izy, 5, 1, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"SRE", c.sre, Izy, 8, 0, true},
+		{"NOP", c.nop, Zpx, 4, 0, true}, {"EOR", c.eor, Zpx, 4, 0, false}, {"LSR", c.lsr, Zpx, 6, 0, false}, {"SRE", c.sre, Zpx, 6, 0, true},
+		{"CLI", c.cli, Imp, 2, 0, false}, {"EOR", c.eor, Aby, 4, 1, false}, {"NOP", c.nop, Imp, 2, 0, true}, {"SRE", c.sre, Aby, 7, 0, true},
+		{"NOP", c.nop, Abx, 4, 1, true}, {"EOR", c.eor, Abx, 4, 1, false}, {"LSR", c.lsr, Abx, 7, 0, false}, {"SRE", c.sre, Abx, 7, 0, true},
+		{"RTS", c.rts, Imp, 6, 0, false}, {"ADC", c.adc, Izx, 6, 0, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"RRA", c.rra, Izx, 8, 0, true},
+		{"NOP", c.nop, Zp0, 3, 0, true}, {"ADC", c.adc, Zp0, 3, 0, false}, {"ROR", c.ror, Zp0, 5, 0, false}, {"RRA", c.rra, Zp0, 5, 0, true},
+		{"PLA", c.pla, Imp, 4, 0, false}, {"ADC", c.adc, Imm, 2, 0, false}, {"ROR", c.ror, Acc, 2, 0, false}, {"ARR", c.arr, Imm, 2, 0, true},
+		{"JMP", c.jmp, Ind, 5, 0, false}, {"ADC", c.adc, Abs, 4, 0, false}, {"ROR", c.ror, Abs, 6, 0, false}, {"RRA", c.rra, Abs, 6, 0, true},
+		{"BVS", c.bvs, Rel, 2, 1, false}, {"ADC", c.adc, Izy, 5, 1, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"RRA", c.rra, Izy, 8, 0, true},
+		{"NOP", c.nop, Zpx, 4, 0, true}, {"ADC", c.adc, Zpx, 4, 0, false}, {"ROR", c.ror, Zpx, 6, 0, false}, {"RRA", c.rra, Zpx, 6, 0, true},
+		{"SEI", c.sei, Imp, 2, 0, false}, {"ADC", c.adc, Aby, 4, 1, false}, {"NOP", c.nop, Imp, 2, 0, true}, {"RRA", c.rra, Aby, 7, 0, true},
+		{"NOP", c.nop, Abx, 4, 1, true}, {"ADC", c.adc, Abx, 4, 1, false}, {"ROR", c.ror, Abx, 7, 0, false}, {"RRA", c.rra, Abx, 7, 0, true},
+		{"NOP", c.nop, Imm, 2, 0, true}, {"STA", c.sta, Izx, 6, 0, false}, {"NOP", c.nop, Imm, 2, 0, true}, {"SAX", c.sax, Izx, 6, 0, true},
+		{"STY", c.sty, Zp0, 3, 0, false}, {"STA", c.sta, Zp0, 3, 0, false}, {"STX", c.stx, Zp0, 3, 0, false}, {"SAX", c.sax, Zp0, 3, 0, true},
+		{"DEY", c.dey, Imp, 2, 0, false}, {"NOP", c.nop, Imm, 2, 0, true}, {"TXA", c.txa, Imp, 2, 0, false}, {"XAA", c.xaa, Imm, 2, 0, true},
+		{"STY", c.sty, Abs, 4, 0, false}, {"STA", c.sta, Abs, 4, 0, false}, {"STX", c.stx, Abs, 4, 0, false}, {"SAX", c.sax, Abs, 4, 0, true},
+		{"BCC", c.bcc, Rel, 2, 1, false}, {"STA", c.sta, Izy, 6, 0, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"SHA", c.sha, Izy, 6, 0, true},
+		{"STY", c.sty, Zpx, 4, 0, false}, {"STA", c.sta, Zpx, 4, 0, false}, {"STX", c.stx, Zpy, 4, 0, false}, {"SAX", c.sax, Zpy, 4, 0, true},
+		{"TYA", c.tya, Imp, 2, 0, false}, {"STA", c.sta, Aby, 5, 0, false}, {"TXS", c.txs, Imp, 2, 0, false}, {"TAS", c.tas, Aby, 5, 0, true},
+		{"SHY", c.shy, Abx, 5, 0, true}, {"STA", c.sta, Abx, 5, 0, false}, {"SHX", c.shx, Aby, 5, 0, true}, {"SHA", c.sha, Aby, 5, 0, true},
+		{"LDY", c.ldy, Imm, 2, 0, false}, {"LDA", c.lda, Izx, 6, 0, false}, {"LDX", c.ldx, Imm, 2, 0, false}, {"LAX", c.lax, Izx, 6, 0, true},
+		{"LDY", c.ldy, Zp0, 3, 0, false}, {"LDA", c.lda, Zp0, 3, 0, false}, {"LDX", c.ldx, Zp0, 3, 0, false}, {"LAX", c.lax, Zp0, 3, 0, true},
+		{"TAY", c.tay, Imp, 2, 0, false}, {"LDA", c.lda, Imm, 2, 0, false}, {"TAX", c.tax, Imp, 2, 0, false}, {"LXA", c.lxa, Imm, 2, 0, true},
+		{"LDY", c.ldy, Abs, 4, 0, false}, {"LDA", c.lda, Abs, 4, 0, false}, {"LDX", c.ldx, Abs, 4, 0, false}, {"LAX", c.lax, Abs, 4, 0, true},
+		{"BCS", c.bcs, Rel, 2, 1, false}, {"LDA", c.lda, Izy, 5, 1, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"LAX", c.lax, Izy, 5, 1, true},
+		{"LDY", c.ldy, Zpx, 4, 0, false}, {"LDA", c.lda, Zpx, 4, 0, false}, {"LDX", c.ldx, Zpy, 4, 0, false}, {"LAX", c.lax, Zpy, 4, 0, true},
+		{"CLV", c.clv, Imp, 2, 0, false}, {"LDA", c.lda, Aby, 4, 1, false}, {"TSX", c.tsx, Imp, 2, 0, false}, {"LAS", c.las, Aby, 4, 1, true},
+		{"LDY", c.ldy, Abx, 4, 1, false}, {"LDA", c.lda, Abx, 4, 1, false}, {"LDX", c.ldx, Aby, 4, 1, false}, {"LAX", c.lax, Aby, 4, 1, true},
+		{"CPY", c.cpy, Imm, 2, 0, false}, {"CMP", c.cmp, Izx, 6, 0, false}, {"NOP", c.nop, Imm, 2, 0, true}, {"DCP", c.dcp, Izx, 8, 0, true},
+		{"CPY", c.cpy, Zp0, 3, 0, false}, {"CMP", c.cmp, Zp0, 3, 0, false}, {"DEC", c.dec, Zp0, 5, 0, false}, {"DCP", c.dcp, Zp0, 5, 0, true},
+		{"INY", c.iny, Imp, 2, 0, false}, {"CMP", c.cmp, Imm, 2, 0, false}, {"DEX", c.dex, Imp, 2, 0, false}, {"AXS", c.axs, Imm, 2, 0, true},
+		{"CPY", c.cpy, Abs, 4, 0, false}, {"CMP", c.cmp, Abs, 4, 0, false}, {"DEC", c.dec, Abs, 6, 0, false}, {"DCP", c.dcp, Abs, 6, 0, true},
+		{"BNE", c.bne, Rel, 2, 1, false}, {"CMP", c.cmp, Izy, 5, 1, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"DCP", c.dcp, Izy, 8, 0, true},
+		{"NOP", c.nop, Zpx, 4, 0, true}, {"CMP", c.cmp, Zpx, 4, 0, false}, {"DEC", c.dec, Zpx, 6, 0, false}, {"DCP", c.dcp, Zpx, 6, 0, true},
+		{"CLD", c.cld, Imp, 2, 0, false}, {"CMP", c.cmp, Aby, 4, 1, false}, {"NOP", c.nop, Imp, 2, 0, true}, {"DCP", c.dcp, Aby, 7, 0, true},
+		{"NOP", c.nop, Abx, 4, 1, true}, {"CMP", c.cmp, Abx, 4, 1, false}, {"DEC", c.dec, Abx, 7, 0, false}, {"DCP", c.dcp, Abx, 7, 0, true},
+		{"CPX", c.cpx, Imm, 2, 0, false}, {"SBC", c.sbc, Izx, 6, 0, false}, {"NOP", c.nop, Imm, 2, 0, true}, {"ISB", c.isb, Izx, 8, 0, true},
+		{"CPX", c.cpx, Zp0, 3, 0, false}, {"SBC", c.sbc, Zp0, 3, 0, false}, {"INC", c.inc, Zp0, 5, 0, false}, {"ISB", c.isb, Zp0, 5, 0, true},
+		{"INX", c.inx, Imp, 2, 0, false}, {"SBC", c.sbc, Imm, 2, 0, false}, {"NOP", c.nop, Imp, 2, 0, false}, {"SBC", c.sbc, Imm, 2, 0, true},
+		{"CPX", c.cpx, Abs, 4, 0, false}, {"SBC", c.sbc, Abs, 4, 0, false}, {"INC", c.inc, Abs, 6, 0, false}, {"ISB", c.isb, Abs, 6, 0, true},
+		{"BEQ", c.beq, Rel, 2, 1, false}, {"SBC", c.sbc, Izy, 5, 1, false}, {"JAM", c.jam, Imp, 2, 0, true}, {"ISB", c.isb, Izy, 8, 0, true},
+		{"NOP", c.nop, Zpx, 4, 0, true}, {"SBC", c.sbc, Zpx, 4, 0, false}, {"INC", c.inc, Zpx, 6, 0, false}, {"ISB", c.isb, Zpx, 6, 0, true},
+		{"SED", c.sed, Imp, 2, 0, false}, {"SBC", c.sbc, Aby, 4, 1, false}, {"NOP", c.nop, Imp, 2, 0, true}, {"ISB", c.isb, Aby, 7, 0, true},
+		{"NOP", c.nop, Abx, 4, 1, true}, {"SBC", c.sbc, Abx, 4, 1, false}, {"INC", c.inc, Abx, 7, 0, false}, {"ISB", c.isb, Abx, 7, 0, true},
 	}
 }
 
-// Future Expansion
-func (c *CPU) fut(data uint8, address uint16) uint8 {
-	return 0
-}
-
+// BRK - Force Interrupt
+// The program counter and processor status are pushed on the stack then the IRQ interrupt vector at $FFFE/F is loaded
+// into the PC and the break flag in the status set to one. The byte following the opcode is padding and is skipped.
+// An NMI arriving early in the sequence hijacks it and the NMI vector is used instead, with B still set on the stack
 func (c *CPU) brk(data uint8, address uint16) uint8 {
+	c.PC++
+	c.pushAddress(c.PC)
+	c.push(flags.SetFlag(c.Status, flags.B|flags.U))
+	c.Status = flags.SetFlag(c.Status, flags.I)
+	c.PC = c.readAddress(irqVector)
+	c.hijackable = true
 	return 0
 }
 
+// ORA - Logical Inclusive OR
 func (c *CPU) ora(data uint8, address uint16) uint8 {
+	c.A |= data
+	c.setZS(c.A)
 	return 0
 }
 
+// ASL - Arithmetic Shift Left
 func (c *CPU) asl(data uint8, address uint16) uint8 {
+	result := data << 1
+	c.setFlag(flags.C, data&0x80 != 0)
+	c.setZS(result)
+	c.writeResult(address, result)
 	return 0
 }
 
+// PHP - Push Processor Status
+// The break and unused flags are always set in the pushed copy of the status register
 func (c *CPU) php(data uint8, address uint16) uint8 {
+	c.push(flags.SetFlag(c.Status, flags.B|flags.U))
 	return 0
 }
 
+// BPL - Branch if Positive
 func (c *CPU) bpl(data uint8, address uint16) uint8 {
-	return 0
+	return c.branch(!flags.IsFlagSet(c.Status, flags.S), address)
 }
 
+// CLC - Clear Carry Flag
 func (c *CPU) clc(data uint8, address uint16) uint8 {
+	c.Status = flags.ClearFlag(c.Status, flags.C)
 	return 0
 }
 
+// JSR - Jump to Subroutine
+// The address of the last byte of the JSR instruction is pushed on to the stack. The CPU reads the low byte of the
+// target, pushes the return address and only then reads the high byte of the target
 func (c *CPU) jsr(data uint8, address uint16) uint8 {
+	lowByte := c.Read(c.PC)
+	c.PC++
+	c.Read(stackBase | uint16(c.SP))
+	c.pushAddress(c.PC)
+	hiByte := c.Read(c.PC)
+	c.PC = buildAddress(hiByte, lowByte)
 	return 0
 }
 
+// AND - Logical AND
 func (c *CPU) and(data uint8, address uint16) uint8 {
+	c.A &= data
+	c.setZS(c.A)
 	return 0
 }
 
+// BIT - Bit Test
+// Z is set from A AND M while V and S are copied from bits 6 and 7 of the memory value
 func (c *CPU) bit(data uint8, address uint16) uint8 {
+	c.setFlag(flags.Z, c.A&data == 0)
+	c.setFlag(flags.V, data&0x40 != 0)
+	c.setFlag(flags.S, data&0x80 != 0)
 	return 0
 }
 
+// ROL - Rotate Left
 func (c *CPU) rol(data uint8, address uint16) uint8 {
+	result := data<<1 | c.carry()
+	c.setFlag(flags.C, data&0x80 != 0)
+	c.setZS(result)
+	c.writeResult(address, result)
 	return 0
 }
 
+// PLP - Pull Processor Status
+// The break flag does not exist in the register itself so it is discarded. The unused flag always reads back as 1
 func (c *CPU) plp(data uint8, address uint16) uint8 {
+	c.Read(stackBase | uint16(c.SP))
+	c.Status = c.pullStatus()
+	c.delayedPoll = true
 	return 0
 }
 
+// BMI - Branch if Minus
 func (c *CPU) bmi(data uint8, address uint16) uint8 {
-	return 0
+	return c.branch(flags.IsFlagSet(c.Status, flags.S), address)
 }
 
+// SEC - Set Carry Flag
 func (c *CPU) sec(data uint8, address uint16) uint8 {
+	c.Status = flags.SetFlag(c.Status, flags.C)
 	return 0
 }
 
+// RTI - Return from Interrupt
 func (c *CPU) rti(data uint8, address uint16) uint8 {
+	c.Read(stackBase | uint16(c.SP))
+	c.Status = c.pullStatus()
+	c.PC = c.pullAddress()
 	return 0
 }
 
+// EOR - Exclusive OR
 func (c *CPU) eor(data uint8, address uint16) uint8 {
+	c.A ^= data
+	c.setZS(c.A)
 	return 0
 }
 
+// LSR - Logical Shift Right
 func (c *CPU) lsr(data uint8, address uint16) uint8 {
+	result := data >> 1
+	c.setFlag(flags.C, data&0x01 != 0)
+	c.setZS(result)
+	c.writeResult(address, result)
 	return 0
 }
 
+// PHA - Push Accumulator
 func (c *CPU) pha(data uint8, address uint16) uint8 {
+	c.push(c.A)
 	return 0
 }
 
+// JMP - Jump
 func (c *CPU) jmp(data uint8, address uint16) uint8 {
+	c.PC = address
 	return 0
 }
 
+// BVC - Branch if Overflow Clear
 func (c *CPU) bvc(data uint8, address uint16) uint8 {
-	return 0
+	return c.branch(!flags.IsFlagSet(c.Status, flags.V), address)
 }
 
+// CLI - Clear Interrupt Disable
 func (c *CPU) cli(data uint8, address uint16) uint8 {
+	c.Status = flags.ClearFlag(c.Status, flags.I)
+	c.delayedPoll = true
 	return 0
 }
 
+// RTS - Return from Subroutine
+// The pulled address is the last byte of the JSR instruction. It is read and discarded while being incremented
 func (c *CPU) rts(data uint8, address uint16) uint8 {
+	c.Read(stackBase | uint16(c.SP))
+	returnAddress := c.pullAddress()
+	c.Read(returnAddress)
+	c.PC = returnAddress + 1
 	return 0
 }
 
+// ADC - Add with Carry
+// The 2A03 has no decimal mode so the D flag is ignored
 func (c *CPU) adc(data uint8, address uint16) uint8 {
+	c.addWithCarry(data)
 	return 0
 }
 
+// ROR - Rotate Right
 func (c *CPU) ror(data uint8, address uint16) uint8 {
+	result := data>>1 | c.carry()<<7
+	c.setFlag(flags.C, data&0x01 != 0)
+	c.setZS(result)
+	c.writeResult(address, result)
 	return 0
 }
 
+// PLA - Pull Accumulator
 func (c *CPU) pla(data uint8, address uint16) uint8 {
+	c.Read(stackBase | uint16(c.SP))
+	c.A = c.pull()
+	c.setZS(c.A)
 	return 0
 }
 
+// BVS - Branch if Overflow Set
 func (c *CPU) bvs(data uint8, address uint16) uint8 {
-	return 0
+	return c.branch(flags.IsFlagSet(c.Status, flags.V), address)
 }
 
+// SEI - Set Interrupt Disable
 func (c *CPU) sei(data uint8, address uint16) uint8 {
+	c.Status = flags.SetFlag(c.Status, flags.I)
+	c.delayedPoll = true
 	return 0
 }
 
+// STA - Store Accumulator
 func (c *CPU) sta(data uint8, address uint16) uint8 {
+	c.Write(address, c.A)
 	return 0
 }
 
+// STY - Store Y Register
 func (c *CPU) sty(data uint8, address uint16) uint8 {
+	c.Write(address, c.Y)
 	return 0
 }
 
+// STX - Store X Register
 func (c *CPU) stx(data uint8, address uint16) uint8 {
+	c.Write(address, c.X)
 	return 0
 }
 
+// DEY - Decrement Y Register
 func (c *CPU) dey(data uint8, address uint16) uint8 {
+	c.Y--
+	c.setZS(c.Y)
 	return 0
 }
 
+// TXA - Transfer X to Accumulator
 func (c *CPU) txa(data uint8, address uint16) uint8 {
+	c.A = c.X
+	c.setZS(c.A)
 	return 0
 }
 
+// BCC - Branch if Carry Clear
 func (c *CPU) bcc(data uint8, address uint16) uint8 {
-	return 0
+	return c.branch(!flags.IsFlagSet(c.Status, flags.C), address)
 }
 
+// TYA - Transfer Y to Accumulator
 func (c *CPU) tya(data uint8, address uint16) uint8 {
+	c.A = c.Y
+	c.setZS(c.A)
 	return 0
 }
 
+// TXS - Transfer X to Stack Pointer
+// Unlike the other transfer instructions, TXS does not affect any flags
 func (c *CPU) txs(data uint8, address uint16) uint8 {
+	c.SP = c.X
 	return 0
 }
 
+// LDY - Load Y Register
 func (c *CPU) ldy(data uint8, address uint16) uint8 {
+	c.Y = data
+	c.setZS(c.Y)
 	return 0
 }
 
+// LDA - Load Accumulator
 func (c *CPU) lda(data uint8, address uint16) uint8 {
+	c.A = data
+	c.setZS(c.A)
 	return 0
 }
 
+// LDX - Load X Register
 func (c *CPU) ldx(data uint8, address uint16) uint8 {
+	c.X = data
+	c.setZS(c.X)
 	return 0
 }
 
+// TAY - Transfer Accumulator to Y
 func (c *CPU) tay(data uint8, address uint16) uint8 {
+	c.Y = c.A
+	c.setZS(c.Y)
 	return 0
 }
 
+// TAX - Transfer Accumulator to X
 func (c *CPU) tax(data uint8, address uint16) uint8 {
+	c.X = c.A
+	c.setZS(c.X)
 	return 0
 }
 
+// BCS - Branch if Carry Set
 func (c *CPU) bcs(data uint8, address uint16) uint8 {
-	return 0
+	return c.branch(flags.IsFlagSet(c.Status, flags.C), address)
 }
 
+// CLV - Clear Overflow Flag
 func (c *CPU) clv(data uint8, address uint16) uint8 {
+	c.Status = flags.ClearFlag(c.Status, flags.V)
 	return 0
 }
 
+// TSX - Transfer Stack Pointer to X
 func (c *CPU) tsx(data uint8, address uint16) uint8 {
+	c.X = c.SP
+	c.setZS(c.X)
 	return 0
 }
 
+// CPY - Compare Y Register
 func (c *CPU) cpy(data uint8, address uint16) uint8 {
+	c.compare(c.Y, data)
 	return 0
 }
 
+// CMP - Compare
 func (c *CPU) cmp(data uint8, address uint16) uint8 {
+	c.compare(c.A, data)
 	return 0
 }
 
+// DEC - Decrement Memory
 func (c *CPU) dec(data uint8, address uint16) uint8 {
+	result := data - 1
+	c.setZS(result)
+	c.Write(address, result)
 	return 0
 }
 
+// INY - Increment Y Register
 func (c *CPU) iny(data uint8, address uint16) uint8 {
+	c.Y++
+	c.setZS(c.Y)
 	return 0
 }
 
+// DEX - Decrement X Register
 func (c *CPU) dex(data uint8, address uint16) uint8 {
+	c.X--
+	c.setZS(c.X)
 	return 0
 }
 
+// BNE - Branch if Not Equal
 func (c *CPU) bne(data uint8, address uint16) uint8 {
-	return 0
+	return c.branch(!flags.IsFlagSet(c.Status, flags.Z), address)
 }
 
+// CLD - Clear Decimal Mode
 func (c *CPU) cld(data uint8, address uint16) uint8 {
+	c.Status = flags.ClearFlag(c.Status, flags.D)
 	return 0
 }
 
+// CPX - Compare X Register
 func (c *CPU) cpx(data uint8, address uint16) uint8 {
+	c.compare(c.X, data)
 	return 0
 }
 
+// SBC - Subtract with Carry
+// Subtraction is addition of the one's complement of the operand. The carry flag acts as an inverted borrow
 func (c *CPU) sbc(data uint8, address uint16) uint8 {
+	c.addWithCarry(^data)
 	return 0
 }
 
+// INC - Increment Memory
 func (c *CPU) inc(data uint8, address uint16) uint8 {
+	result := data + 1
+	c.setZS(result)
+	c.Write(address, result)
 	return 0
 }
 
+// INX - Increment X Register
 func (c *CPU) inx(data uint8, address uint16) uint8 {
+	c.X++
+	c.setZS(c.X)
 	return 0
 }
 
+// NOP - No Operation
 func (c *CPU) nop(data uint8, address uint16) uint8 {
 	return 0
 }
 
+// BEQ - Branch if Equal
 func (c *CPU) beq(data uint8, address uint16) uint8 {
-	return 0
+	return c.branch(flags.IsFlagSet(c.Status, flags.Z), address)
 }
 
+// SED - Set Decimal Flag
 func (c *CPU) sed(data uint8, address uint16) uint8 {
+	c.Status = flags.SetFlag(c.Status, flags.D)
 	return 0
 }
+
+// branch moves the program counter to address if condition is true.
+// Taking a branch costs one additional cycle, and a second one if the destination is on a different page. During
+// those cycles the CPU reads the next opcode, then the destination before its high byte has been fixed
+func (c *CPU) branch(condition bool, address uint16) uint8 {
+	if !condition {
+		return 0
+	}
+	var additionalCycles uint8 = 1
+	c.Read(c.PC)
+	if address&0xFF00 != c.PC&0xFF00 {
+		c.Read(c.PC&0xFF00 | address&0x00FF)
+		additionalCycles++
+	}
+	c.PC = address
+	return additionalCycles
+}
+
+// addWithCarry adds data and the carry flag to the accumulator, setting C, V, Z and S
+func (c *CPU) addWithCarry(data uint8) {
+	sum := uint16(c.A) + uint16(data) + uint16(c.carry())
+	result := uint8(sum)
+	c.setFlag(flags.C, sum > 0xFF)
+	// Overflow occurs when both operands have the same sign and the result has a different sign
+	c.setFlag(flags.V, (c.A^result)&(data^result)&0x80 != 0)
+	c.A = result
+	c.setZS(c.A)
+}
+
+// compare sets the flags as if data were subtracted from register
+func (c *CPU) compare(register, data uint8) {
+	c.setFlag(flags.C, register >= data)
+	c.setZS(register - data)
+}
+
+// writeResult stores the result of a read-modify-write instruction in either the accumulator or memory
+// depending on the addressing mode of the current instruction
+func (c *CPU) writeResult(address uint16, result uint8) {
+	if c.addressingMode == Acc {
+		c.A = result
+		return
+	}
+	c.Write(address, result)
+}
@@ -0,0 +1,90 @@
+package cpu6502
+
+import "github.com/kevinwmiller/nesgo/nes/cpu6502/flags"
+
+// IRQSource identifies a device that drives the shared, active low IRQ line. Every source asserts and releases the
+// line independently and the CPU sees an interrupt request for as long as at least one source is asserting it
+type IRQSource uint8
+
+// Known IRQ sources
+const (
+	IRQMapper IRQSource = 1 << iota
+	IRQFrameCounter
+	IRQDMC
+	IRQExpansion
+)
+
+// The number of cycles taken by the NMI, IRQ and BRK interrupt sequences
+const interruptCycles = 7
+
+// If an NMI is asserted within the first four cycles of a BRK or IRQ sequence, the sequence finishes as normal but
+// the CPU fetches the NMI vector instead of the IRQ vector
+const hijackCycles = 4
+
+// SetNMI sets the level of the NMI line. NMI is edge triggered so an interrupt is only requested when the line goes
+// from released to asserted. Holding the line asserted will not cause further interrupts
+func (c *CPU) SetNMI(asserted bool) {
+	if asserted && !c.nmiLine {
+		c.nmiPending = true
+	}
+	c.nmiLine = asserted
+}
+
+// SetIRQ asserts or releases the IRQ line on behalf of source. IRQ is level triggered and will be serviced at every
+// instruction boundary while any source holds the line and the interrupt disable flag is clear
+func (c *CPU) SetIRQ(source IRQSource, asserted bool) {
+	if asserted {
+		c.irqLine |= source
+	} else {
+		c.irqLine &^= source
+	}
+}
+
+// IRQ returns true if any source is currently asserting the IRQ line
+func (c *CPU) IRQ() bool {
+	return c.irqLine != 0
+}
+
+// pollInterrupts is called at an instruction boundary and starts the interrupt sequence for any pending interrupt.
+// It returns true if an interrupt sequence was started
+func (c *CPU) pollInterrupts() bool {
+	if c.nmiPending {
+		c.nmiPending = false
+		c.interrupt(nmiVector, false)
+		return true
+	}
+	if c.irqLine != 0 && !flags.IsFlagSet(c.pollStatus, flags.I) {
+		c.interrupt(irqVector, true)
+		return true
+	}
+	return false
+}
+
+// interrupt pushes the program counter and the status register, with the break flag clear, then jumps to the
+// address stored in vector. The sequence starts with two discarded reads of the next opcode
+func (c *CPU) interrupt(vector uint16, hijackable bool) {
+	c.Read(c.PC)
+	c.Read(c.PC)
+	c.pushAddress(c.PC)
+	c.push(flags.SetFlag(flags.ClearFlag(c.Status, flags.B), flags.U))
+	c.Status = flags.SetFlag(c.Status, flags.I)
+	c.pollStatus = c.Status
+	c.PC = c.readAddress(vector)
+	c.cycles = interruptCycles
+	c.hijackable = hijackable
+}
+
+// hijack redirects a BRK or IRQ sequence that is still in progress to the NMI vector if an NMI has arrived early
+// enough for the CPU to see it before fetching the interrupt vector
+func (c *CPU) hijack() {
+	if !c.hijackable || !c.nmiPending {
+		return
+	}
+	if interruptCycles-c.cycles > hijackCycles {
+		c.hijackable = false
+		return
+	}
+	c.nmiPending = false
+	c.hijackable = false
+	c.PC = c.readAddress(nmiVector)
+}
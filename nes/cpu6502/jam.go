@@ -0,0 +1,52 @@
+package cpu6502
+
+import "fmt"
+
+// JamBehavior controls how the CPU reacts to one of the KIL/JAM opcodes
+type JamBehavior uint8
+
+const (
+	// HaltOnJam freezes the CPU until it is reset, which is what the hardware does
+	HaltOnJam JamBehavior = iota
+	// ErrorOnJam freezes the CPU and reports a JamError through Err so that the host can stop emulation
+	ErrorOnJam
+)
+
+// JamError is reported when the CPU executes a JAM opcode while configured with ErrorOnJam
+type JamError struct {
+	Opcode  uint8
+	Address uint16
+}
+
+func (e *JamError) Error() string {
+	return fmt.Sprintf("cpu jammed by opcode $%02X at $%04X", e.Opcode, e.Address)
+}
+
+// SetJamBehavior configures how the CPU reacts to a JAM opcode
+func (c *CPU) SetJamBehavior(behavior JamBehavior) {
+	c.jamBehavior = behavior
+}
+
+// Jammed returns true if the CPU has been halted by a JAM opcode
+func (c *CPU) Jammed() bool {
+	return c.jammed
+}
+
+// Err returns the error that stopped the CPU, if any
+func (c *CPU) Err() error {
+	return c.err
+}
+
+// JAM - Halt the CPU. Also known as KIL or HLT
+// The data bus is stuck at $FF and the processor stops fetching instructions. Only a reset will recover it
+func (c *CPU) jam(data uint8, address uint16) uint8 {
+	c.PC--
+	c.jammed = true
+	if c.jamBehavior == ErrorOnJam {
+		c.err = &JamError{
+			Opcode:  c.Read(c.PC),
+			Address: c.PC,
+		}
+	}
+	return 0
+}
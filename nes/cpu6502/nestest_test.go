@@ -0,0 +1,132 @@
+package cpu6502_test
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kevinwmiller/nesgo/nes/bus"
+	"github.com/kevinwmiller/nesgo/nes/cartridge"
+	"github.com/kevinwmiller/nesgo/nes/cpu6502"
+)
+
+const (
+	nestestROM = "nestest.nes"
+	nestestLog = "nestest.log"
+	// In automation mode nestest starts at $C000 instead of the reset vector and runs without a PPU
+	nestestStart = 0xC000
+	// The number of lines shown before a divergent line when the test fails
+	nestestContext = 10
+	// Give up if the CPU never reaches the end of the reference log
+	nestestMaxCycles = 100000
+)
+
+// The test runs without a PPU so the PPU column is removed from both logs before they are compared
+var ppuColumn = regexp.MustCompile(`PPU:\s*-?\d+,\s*-?\d+ `)
+
+// lineRecorder collects the lines written by the CPU tracer
+type lineRecorder struct {
+	lines   []string
+	partial []byte
+}
+
+func (r *lineRecorder) Write(p []byte) (int, error) {
+	r.partial = append(r.partial, p...)
+	for {
+		i := bytes.IndexByte(r.partial, '\n')
+		if i < 0 {
+			break
+		}
+		r.lines = append(r.lines, string(r.partial[:i]))
+		r.partial = r.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// loadNestest maps the nestest cartridge onto a bus
+func loadNestest(t *testing.T, path string) *bus.Bus {
+	cart, err := cartridge.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bus.New()
+	b.Map(bus.CartridgeStart, bus.CartridgeEnd, 0, cart)
+	return b
+}
+
+func readReferenceLog(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func TestNestest(t *testing.T) {
+	romPath := filepath.Join("testdata", nestestROM)
+	logPath := filepath.Join("testdata", nestestLog)
+	for _, path := range []string{romPath, logPath} {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			t.Skipf("%s not found. Copy nestest.nes and nestest.log from https://www.qmtpro.com/~nes/misc/ into testdata", path)
+		}
+	}
+
+	reference := readReferenceLog(t, logPath)
+
+	cpu := cpu6502.NewCPU()
+	cpu.ConnectBus(loadNestest(t, romPath))
+	cpu.Reset()
+	cpu.PC = nestestStart
+
+	trace := &lineRecorder{}
+	cpu.Trace(trace)
+
+	for cpu.Cycles() < nestestMaxCycles && len(trace.lines) < len(reference) && !cpu.Jammed() {
+		cpu.Tick()
+	}
+
+	for i, want := range reference {
+		if i >= len(trace.lines) {
+			t.Fatalf("trace ended after %d of %d lines", len(trace.lines), len(reference))
+		}
+		got := trace.lines[i]
+		if ppuColumn.ReplaceAllString(got, "") == ppuColumn.ReplaceAllString(want, "") {
+			continue
+		}
+
+		var context strings.Builder
+		start := i - nestestContext
+		if start < 0 {
+			start = 0
+		}
+		for _, line := range trace.lines[start:i] {
+			context.WriteString("      " + line + "\n")
+		}
+		t.Fatalf("trace diverges from %s at line %d\n%s got:  %s\n want: %s", nestestLog, i+1, context.String(), got, want)
+	}
+
+	// nestest stores the result of the official and unofficial opcode tests at $02 and $03
+	if result := cpu.Read(0x0002); result != 0 {
+		t.Errorf("official opcode tests failed with code $%02X", result)
+	}
+	if result := cpu.Read(0x0003); result != 0 {
+		t.Errorf("unofficial opcode tests failed with code $%02X", result)
+	}
+}
@@ -0,0 +1,187 @@
+package cpu6502_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kevinwmiller/nesgo/nes/cpu6502"
+)
+
+// Set PROCESSOR_TESTS to a directory holding the nes6502 per-opcode JSON files from
+// https://github.com/TomHarte/ProcessorTests to run the full suite in addition to the vectors in testdata
+const processorTestsEnv = "PROCESSOR_TESTS"
+
+// JAM opcodes lock up the CPU so there is no meaningful final state to compare against
+var jamOpcodes = map[string]bool{
+	"02": true, "12": true, "22": true, "32": true, "42": true, "52": true,
+	"62": true, "72": true, "92": true, "b2": true, "d2": true, "f2": true,
+}
+
+type processorTest struct {
+	Name    string         `json:"name"`
+	Initial processorState `json:"initial"`
+	Final   processorState `json:"final"`
+	Cycles  []busCycle     `json:"cycles"`
+}
+
+type processorState struct {
+	PC  uint16      `json:"pc"`
+	S   uint8       `json:"s"`
+	A   uint8       `json:"a"`
+	X   uint8       `json:"x"`
+	Y   uint8       `json:"y"`
+	P   uint8       `json:"p"`
+	RAM [][2]uint16 `json:"ram"`
+}
+
+// busCycle is a single bus access. In the test files it is encoded as [address, value, "read" | "write"]
+type busCycle struct {
+	Address uint16
+	Value   uint8
+	Kind    string
+}
+
+func (c *busCycle) UnmarshalJSON(data []byte) error {
+	var fields []interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return fmt.Errorf("expected 3 fields in bus cycle, got %d", len(fields))
+	}
+	address, addressOk := fields[0].(float64)
+	value, valueOk := fields[1].(float64)
+	kind, kindOk := fields[2].(string)
+	if !addressOk || !valueOk || !kindOk {
+		return fmt.Errorf("malformed bus cycle %s", data)
+	}
+	c.Address = uint16(address)
+	c.Value = uint8(value)
+	c.Kind = kind
+	return nil
+}
+
+func (c busCycle) String() string {
+	return fmt.Sprintf("%-5s $%04X = %02X", c.Kind, c.Address, c.Value)
+}
+
+// recordingBus is a flat 64KB memory that records every access made to it
+type recordingBus struct {
+	ram    [0x10000]uint8
+	cycles []busCycle
+}
+
+func (b *recordingBus) Read(address uint16) uint8 {
+	data := b.ram[address]
+	b.cycles = append(b.cycles, busCycle{address, data, "read"})
+	return data
+}
+
+func (b *recordingBus) Write(address uint16, data uint8) {
+	b.ram[address] = data
+	b.cycles = append(b.cycles, busCycle{address, data, "write"})
+}
+
+func processorTestFiles(t *testing.T) []string {
+	files, err := filepath.Glob(filepath.Join("testdata", "processortests", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir := os.Getenv(processorTestsEnv); dir != "" {
+		suite, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, suite...)
+	}
+	return files
+}
+
+func TestProcessorTests(t *testing.T) {
+	for _, file := range processorTestFiles(t) {
+		opcode := strings.TrimSuffix(filepath.Base(file), ".json")
+		if jamOpcodes[opcode] {
+			continue
+		}
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tests []processorTest
+			if err := json.Unmarshal(data, &tests); err != nil {
+				t.Fatal(err)
+			}
+			for _, test := range tests {
+				if err := runProcessorTest(test); err != nil {
+					t.Fatalf("%s: %v", test.Name, err)
+				}
+			}
+		})
+	}
+}
+
+func runProcessorTest(test processorTest) error {
+	b := &recordingBus{}
+	cpu := cpu6502.NewCPU()
+	cpu.ConnectBus(b)
+	cpu.Reset()
+
+	for _, entry := range test.Initial.RAM {
+		b.ram[entry[0]] = uint8(entry[1])
+	}
+	cpu.PC = test.Initial.PC
+	cpu.SP = test.Initial.S
+	cpu.A = test.Initial.A
+	cpu.X = test.Initial.X
+	cpu.Y = test.Initial.Y
+	cpu.Status = test.Initial.P
+	// Finish the reset sequence before recording so that only the instruction under test is captured
+	for cpu.Cycles() < 7 {
+		cpu.Tick()
+	}
+	b.cycles = nil
+
+	cycles := cpu.Step()
+
+	var errs []string
+	check := func(name string, got, want interface{}) {
+		if got != want {
+			errs = append(errs, fmt.Sprintf("%s = %v, want %v", name, got, want))
+		}
+	}
+	check("PC", fmt.Sprintf("$%04X", cpu.PC), fmt.Sprintf("$%04X", test.Final.PC))
+	check("SP", fmt.Sprintf("$%02X", cpu.SP), fmt.Sprintf("$%02X", test.Final.S))
+	check("A", fmt.Sprintf("$%02X", cpu.A), fmt.Sprintf("$%02X", test.Final.A))
+	check("X", fmt.Sprintf("$%02X", cpu.X), fmt.Sprintf("$%02X", test.Final.X))
+	check("Y", fmt.Sprintf("$%02X", cpu.Y), fmt.Sprintf("$%02X", test.Final.Y))
+	check("P", fmt.Sprintf("%08b", cpu.Status), fmt.Sprintf("%08b", test.Final.P))
+	for _, entry := range test.Final.RAM {
+		check(fmt.Sprintf("RAM[$%04X]", entry[0]), fmt.Sprintf("$%02X", b.ram[entry[0]]), fmt.Sprintf("$%02X", entry[1]))
+	}
+	check("cycles", cycles, len(test.Cycles))
+
+	for i := 0; i < len(test.Cycles) || i < len(b.cycles); i++ {
+		var got, want string
+		if i < len(b.cycles) {
+			got = b.cycles[i].String()
+		}
+		if i < len(test.Cycles) {
+			want = test.Cycles[i].String()
+		}
+		if got != want {
+			errs = append(errs, fmt.Sprintf("bus cycle %d = %q, want %q", i+1, got, want))
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("\n\t%s", strings.Join(errs, "\n\t"))
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package cpu6502
+
+// The stack lives on page one ($0100-$01FF) and grows downward. SP holds the low byte of the next free location.
+const stackBase uint16 = 0x0100
+
+// push writes data to the top of the stack and decrements the stack pointer
+func (c *CPU) push(data uint8) {
+	c.Write(stackBase|uint16(c.SP), data)
+	c.SP--
+}
+
+// pull increments the stack pointer and returns the data at the top of the stack
+func (c *CPU) pull() uint8 {
+	c.SP++
+	return c.Read(stackBase | uint16(c.SP))
+}
+
+// pushAddress pushes a 16 bit address on to the stack, high byte first
+func (c *CPU) pushAddress(address uint16) {
+	c.push(uint8(address >> 8))
+	c.push(uint8(address))
+}
+
+// pullAddress pulls a 16 bit address off of the stack, low byte first
+func (c *CPU) pullAddress() uint16 {
+	lowByte := c.pull()
+	hiByte := c.pull()
+	return buildAddress(hiByte, lowByte)
+}
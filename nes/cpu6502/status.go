@@ -0,0 +1,33 @@
+package cpu6502
+
+import "github.com/kevinwmiller/nesgo/nes/cpu6502/flags"
+
+// setFlag sets or clears a single flag in the status register
+func (c *CPU) setFlag(flag uint8, set bool) {
+	if set {
+		c.Status = flags.SetFlag(c.Status, flag)
+	} else {
+		c.Status = flags.ClearFlag(c.Status, flag)
+	}
+}
+
+// setZS updates the zero and sign flags based on value
+func (c *CPU) setZS(value uint8) {
+	c.setFlag(flags.Z, value == 0)
+	c.setFlag(flags.S, value&0x80 != 0)
+}
+
+// carry returns the carry flag as a 0 or 1 so that it can be used in arithmetic
+func (c *CPU) carry() uint8 {
+	if flags.IsFlagSet(c.Status, flags.C) {
+		return 1
+	}
+	return 0
+}
+
+// pullStatus pulls the status register off of the stack. The break flag only exists in copies of the status register
+// that have been pushed to the stack so it is ignored, and the unused flag is always set
+func (c *CPU) pullStatus() uint8 {
+	status := c.pull()
+	return flags.SetFlag(flags.ClearFlag(status, flags.B), flags.U)
+}
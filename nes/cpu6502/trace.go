@@ -0,0 +1,159 @@
+package cpu6502
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kevinwmiller/nesgo/nes/bus"
+)
+
+// PPUPosition reports the scanline and dot the PPU is currently on. It is only used to annotate trace output
+type PPUPosition func() (scanline, dot int)
+
+// tracer writes one line per executed instruction in the format of the nestest.log reference log
+//
+//	C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7
+type tracer struct {
+	w           io.Writer
+	ppuPosition PPUPosition
+}
+
+// Trace enables instruction tracing to w. Passing nil disables tracing. Tracing can be toggled at any time and costs
+// nothing while disabled
+func (c *CPU) Trace(w io.Writer) {
+	if w == nil {
+		c.tracer = nil
+		return
+	}
+	c.tracer = &tracer{
+		w:           w,
+		ppuPosition: c.ppuPosition,
+	}
+}
+
+// SetPPUPosition sets the function used to report the PPU scanline and dot in trace output
+func (c *CPU) SetPPUPosition(position PPUPosition) {
+	c.ppuPosition = position
+	if c.tracer != nil {
+		c.tracer.ppuPosition = position
+	}
+}
+
+// trace writes the trace line for the instruction at the program counter. It must be called before the instruction
+// is fetched so that the registers reflect the state the instruction starts with
+func (c *CPU) trace() {
+	opcode := c.peek(c.PC)
+	instruction := &c.instructions[opcode]
+
+	size := instructionSizes[instruction.AddressingMode]
+	var bytes string
+	for i := uint16(0); i < size; i++ {
+		bytes += fmt.Sprintf("%02X ", c.peek(c.PC+i))
+	}
+
+	prefix := ' '
+	if instruction.Unofficial {
+		prefix = '*'
+	}
+	disassembly := fmt.Sprintf("%c%s %s", prefix, instruction.Name, c.disassembleOperand(instruction))
+
+	var scanline, dot int
+	if c.tracer.ppuPosition != nil {
+		scanline, dot = c.tracer.ppuPosition()
+	}
+
+	fmt.Fprintf(c.tracer.w, "%04X  %-9s%-33sA:%02X X:%02X Y:%02X P:%02X SP:%02X PPU:%3d,%3d CYC:%d\n",
+		c.PC,
+		bytes,
+		disassembly,
+		c.A,
+		c.X,
+		c.Y,
+		c.Status,
+		c.SP,
+		scanline,
+		dot,
+		c.cycleCount,
+	)
+}
+
+// The number of bytes, including the opcode, used by an instruction in each addressing mode
+var instructionSizes = [...]uint16{
+	Und: 1,
+	Imp: 1,
+	Acc: 1,
+	Imm: 2,
+	Zp0: 2,
+	Zpx: 2,
+	Zpy: 2,
+	Abs: 3,
+	Abx: 3,
+	Aby: 3,
+	Rel: 2,
+	Izx: 2,
+	Izy: 2,
+	Ind: 3,
+}
+
+// disassembleOperand formats the operand of the instruction at the program counter. Effective addresses and the
+// values stored at them are resolved using the current register values
+func (c *CPU) disassembleOperand(instruction *Instruction) string {
+	operand := c.peek(c.PC + 1)
+	address := buildAddress(c.peek(c.PC+2), operand)
+
+	switch instruction.AddressingMode {
+	case Acc:
+		return "A"
+	case Imm:
+		return fmt.Sprintf("#$%02X", operand)
+	case Zp0:
+		return fmt.Sprintf("$%02X = %02X", operand, c.peek(uint16(operand)))
+	case Zpx:
+		effective := operand + c.X
+		return fmt.Sprintf("$%02X,X @ %02X = %02X", operand, effective, c.peek(uint16(effective)))
+	case Zpy:
+		effective := operand + c.Y
+		return fmt.Sprintf("$%02X,Y @ %02X = %02X", operand, effective, c.peek(uint16(effective)))
+	case Abs:
+		if instruction.Name == "JMP" || instruction.Name == "JSR" {
+			return fmt.Sprintf("$%04X", address)
+		}
+		return fmt.Sprintf("$%04X = %02X", address, c.peek(address))
+	case Abx:
+		effective := address + uint16(c.X)
+		return fmt.Sprintf("$%04X,X @ %04X = %02X", address, effective, c.peek(effective))
+	case Aby:
+		effective := address + uint16(c.Y)
+		return fmt.Sprintf("$%04X,Y @ %04X = %02X", address, effective, c.peek(effective))
+	case Rel:
+		return fmt.Sprintf("$%04X", c.PC+2+uint16(int8(operand)))
+	case Izx:
+		ptr := operand + c.X
+		effective := buildAddress(c.peek(uint16(ptr+1)), c.peek(uint16(ptr)))
+		return fmt.Sprintf("($%02X,X) @ %02X = %04X = %02X", operand, ptr, effective, c.peek(effective))
+	case Izy:
+		base := buildAddress(c.peek(uint16(operand+1)), c.peek(uint16(operand)))
+		effective := base + uint16(c.Y)
+		return fmt.Sprintf("($%02X),Y = %04X @ %04X = %02X", operand, base, effective, c.peek(effective))
+	case Ind:
+		// Mirror the page wrapping bug of the indirect addressing mode
+		hiAddress := address&0xFF00 | uint16(uint8(address)+1)
+		effective := buildAddress(c.peek(hiAddress), c.peek(address))
+		return fmt.Sprintf("($%04X) = %04X", address, effective)
+	}
+	return ""
+}
+
+// peek reads memory for tracing purposes without disturbing the emulation. The PPU and I/O registers are shown as
+// $FF, as in the reference logs, and so is everything else when the bus can't be read without side effects
+func (c *CPU) peek(address uint16) uint8 {
+	if address >= bus.PPUStart && address <= bus.IOEnd {
+		return 0xFF
+	}
+	p, ok := c.bus.(bus.Peeker)
+	if !ok {
+		return 0xFF
+	}
+	data, _ := p.Peek(address)
+	return data
+}
@@ -0,0 +1,165 @@
+package cpu6502
+
+import "github.com/kevinwmiller/nesgo/nes/cpu6502/flags"
+
+// Unofficial opcodes
+// Descriptions from http://www.oxyron.de/html/opcodes02.html and http://wiki.nesdev.com/w/index.php/Programming_with_unofficial_opcodes
+
+// Most of the "magic" constant used by the unstable XAA and LXA instructions. The value varies between chips and
+// temperature. $EE matches the behaviour most commonly observed on a 2A03
+const unstableMagic uint8 = 0xEE
+
+// SLO - ASL followed by ORA
+func (c *CPU) slo(data uint8, address uint16) uint8 {
+	c.asl(data, address)
+	c.ora(data<<1, address)
+	return 0
+}
+
+// RLA - ROL followed by AND
+func (c *CPU) rla(data uint8, address uint16) uint8 {
+	result := data<<1 | c.carry()
+	c.rol(data, address)
+	c.and(result, address)
+	return 0
+}
+
+// SRE - LSR followed by EOR
+func (c *CPU) sre(data uint8, address uint16) uint8 {
+	c.lsr(data, address)
+	c.eor(data>>1, address)
+	return 0
+}
+
+// RRA - ROR followed by ADC
+func (c *CPU) rra(data uint8, address uint16) uint8 {
+	result := data>>1 | c.carry()<<7
+	c.ror(data, address)
+	c.adc(result, address)
+	return 0
+}
+
+// DCP - DEC followed by CMP
+func (c *CPU) dcp(data uint8, address uint16) uint8 {
+	c.dec(data, address)
+	c.cmp(data-1, address)
+	return 0
+}
+
+// ISB - INC followed by SBC. Also known as ISC
+func (c *CPU) isb(data uint8, address uint16) uint8 {
+	c.inc(data, address)
+	c.sbc(data+1, address)
+	return 0
+}
+
+// SAX - Store A AND X
+func (c *CPU) sax(data uint8, address uint16) uint8 {
+	c.Write(address, c.A&c.X)
+	return 0
+}
+
+// LAX - LDA followed by TAX
+func (c *CPU) lax(data uint8, address uint16) uint8 {
+	c.A = data
+	c.X = data
+	c.setZS(data)
+	return 0
+}
+
+// ANC - AND followed by copying the sign flag into the carry flag
+func (c *CPU) anc(data uint8, address uint16) uint8 {
+	c.and(data, address)
+	c.setFlag(flags.C, flags.IsFlagSet(c.Status, flags.S))
+	return 0
+}
+
+// ALR - AND followed by LSR A. Also known as ASR
+func (c *CPU) alr(data uint8, address uint16) uint8 {
+	value := c.A & data
+	c.setFlag(flags.C, value&0x01 != 0)
+	c.A = value >> 1
+	c.setZS(c.A)
+	return 0
+}
+
+// ARR - AND followed by ROR A. The carry and overflow flags come from bits 6 and 5 of the result rather than from the
+// rotate because the adder is involved in the operation
+func (c *CPU) arr(data uint8, address uint16) uint8 {
+	c.A = (c.A&data)>>1 | c.carry()<<7
+	c.setZS(c.A)
+	c.setFlag(flags.C, c.A&0x40 != 0)
+	c.setFlag(flags.V, (c.A>>6^c.A>>5)&0x01 != 0)
+	return 0
+}
+
+// AXS - X = (A AND X) - immediate, without borrow. Flags are set as in CMP. Also known as SBX
+func (c *CPU) axs(data uint8, address uint16) uint8 {
+	value := c.A & c.X
+	c.setFlag(flags.C, value >= data)
+	c.X = value - data
+	c.setZS(c.X)
+	return 0
+}
+
+// XAA - TXA followed by AND immediate. Highly unstable. Also known as ANE
+func (c *CPU) xaa(data uint8, address uint16) uint8 {
+	c.A = (c.A | unstableMagic) & c.X & data
+	c.setZS(c.A)
+	return 0
+}
+
+// LXA - LDA immediate followed by TAX. Unstable. Also known as LAX immediate or ATX
+func (c *CPU) lxa(data uint8, address uint16) uint8 {
+	c.A = (c.A | unstableMagic) & data
+	c.X = c.A
+	c.setZS(c.A)
+	return 0
+}
+
+// LAS - A, X and SP are all set to memory AND SP. Also known as LAR
+func (c *CPU) las(data uint8, address uint16) uint8 {
+	value := data & c.SP
+	c.A = value
+	c.X = value
+	c.SP = value
+	c.setZS(value)
+	return 0
+}
+
+// SHA - Store A AND X AND (high byte of the base address + 1). Also known as AHX or AXA
+func (c *CPU) sha(data uint8, address uint16) uint8 {
+	c.unstableStore(c.A&c.X, address, c.Y)
+	return 0
+}
+
+// SHX - Store X AND (high byte of the base address + 1). Also known as SXA or XAS
+func (c *CPU) shx(data uint8, address uint16) uint8 {
+	c.unstableStore(c.X, address, c.Y)
+	return 0
+}
+
+// SHY - Store Y AND (high byte of the base address + 1). Also known as SYA or SAY
+func (c *CPU) shy(data uint8, address uint16) uint8 {
+	c.unstableStore(c.Y, address, c.X)
+	return 0
+}
+
+// TAS - SP = A AND X, then store SP AND (high byte of the base address + 1). Also known as XAS or SHS
+func (c *CPU) tas(data uint8, address uint16) uint8 {
+	c.SP = c.A & c.X
+	c.unstableStore(c.SP, address, c.Y)
+	return 0
+}
+
+// unstableStore implements the shared behaviour of SHA, SHX, SHY and TAS. The value written is ANDed with the high
+// byte of the base address plus one. When indexing crosses a page, the high byte of the target address is corrupted
+// and replaced by the value being written
+func (c *CPU) unstableStore(value uint8, address uint16, index uint8) {
+	base := address - uint16(index)
+	value &= uint8(base>>8) + 1
+	if base&0xFF00 != address&0xFF00 {
+		address = uint16(value)<<8 | address&0x00FF
+	}
+	c.Write(address, value)
+}
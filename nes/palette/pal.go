@@ -0,0 +1,55 @@
+package palette
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io/ioutil"
+)
+
+// A .pal file is a list of 3 byte RGB colours, either the 64 base colours or all 512 colours with every combination of
+// the emphasis bits in order
+const (
+	palColourSize = 3
+	palSize       = Colours * palColourSize
+	palFullSize   = Size * palColourSize
+)
+
+// ErrMalformed is returned for palette files that are not a valid size
+var ErrMalformed = errors.New("malformed palette")
+
+// Load reads a palette from a .pal file. 64 colour files are extended with emphasis by dimming the colours
+func Load(path string) (*Palette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse parses the contents of a .pal file
+func Parse(data []byte) (*Palette, error) {
+	switch len(data) {
+	case palSize:
+		var colours [Colours]color.RGBA
+		for i := range colours {
+			colours[i] = palColour(data[i*palColourSize:])
+		}
+		return extend(&colours), nil
+	case palFullSize:
+		p := &Palette{}
+		for i := range p {
+			p[i] = palColour(data[i*palColourSize:])
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w: file is %d bytes, expected %d or %d", ErrMalformed, len(data), palSize, palFullSize)
+}
+
+func palColour(data []byte) color.RGBA {
+	return color.RGBA{R: data[0], G: data[1], B: data[2], A: 0xFF}
+}
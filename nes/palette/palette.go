@@ -0,0 +1,95 @@
+// Package palette converts the pixels rendered by the PPU into RGB colours.
+// http://wiki.nesdev.com/w/index.php/PPU_palettes
+package palette
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/kevinwmiller/nesgo/nes/ppu"
+)
+
+// Size of a palette. Each PPU pixel is a 6 bit colour combined with the 3 colour emphasis bits of PPUMASK
+const (
+	Colours = 64
+	Size    = Colours * 8
+)
+
+// Emphasis bits of a pixel, above the colour
+const (
+	emphasisRed   = 0x40
+	emphasisGreen = 0x80
+	emphasisBlue  = 0x100
+)
+
+// emphasisAttenuation is how much the channels that are not emphasised are dimmed when a 64 colour palette is extended
+// with emphasis
+const emphasisAttenuation = 0.816
+
+// Palette maps every pixel value the PPU can produce to an RGB colour
+type Palette [Size]color.RGBA
+
+// ntsc is the default palette, as measured from a 2C02
+var ntsc = [Colours]uint32{
+	0x666666, 0x002A88, 0x1412A7, 0x3B00A4, 0x5C007E, 0x6E0040, 0x6C0600, 0x561D00,
+	0x333500, 0x0B4800, 0x005200, 0x004F08, 0x00404D, 0x000000, 0x000000, 0x000000,
+	0xADADAD, 0x155FD9, 0x4240FF, 0x7527FE, 0xA01ACC, 0xB71E7B, 0xB53120, 0x994E00,
+	0x6B6D00, 0x388700, 0x0C9300, 0x008F32, 0x007C8D, 0x000000, 0x000000, 0x000000,
+	0xFFFEFF, 0x64B0FF, 0x9290FF, 0xC676FF, 0xF36AFF, 0xFE6ECC, 0xFE8170, 0xEA9E22,
+	0xBCBE00, 0x88D800, 0x5CE430, 0x45E082, 0x48CDDE, 0x4F4F4F, 0x000000, 0x000000,
+	0xFFFEFF, 0xC0DFFF, 0xD3D2FF, 0xE8C8FF, 0xFBC2FF, 0xFEC4EA, 0xFECCC5, 0xF7D8A5,
+	0xE4E594, 0xCFEF96, 0xBDF4AB, 0xB3F3CC, 0xB5EBF2, 0xB8B8B8, 0x000000, 0x000000,
+}
+
+// Default returns the built in NTSC palette
+func Default() *Palette {
+	var colours [Colours]color.RGBA
+	for i, rgb := range ntsc {
+		colours[i] = color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xFF}
+	}
+	return extend(&colours)
+}
+
+// extend builds a full palette from 64 colours by dimming the channels that are not emphasised
+func extend(colours *[Colours]color.RGBA) *Palette {
+	p := &Palette{}
+	for pixel := range p {
+		c := colours[pixel%Colours]
+		r, g, b := float64(c.R), float64(c.G), float64(c.B)
+		if pixel&emphasisRed != 0 {
+			g *= emphasisAttenuation
+			b *= emphasisAttenuation
+		}
+		if pixel&emphasisGreen != 0 {
+			r *= emphasisAttenuation
+			b *= emphasisAttenuation
+		}
+		if pixel&emphasisBlue != 0 {
+			r *= emphasisAttenuation
+			g *= emphasisAttenuation
+		}
+		p[pixel] = color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 0xFF}
+	}
+	return p
+}
+
+// Colour returns the RGB colour of a pixel from a ppu.Frame
+func (p *Palette) Colour(pixel uint16) color.RGBA {
+	return p[pixel%Size]
+}
+
+// Image converts a frame to an RGB image
+func (p *Palette) Image(frame *ppu.Frame) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, ppu.Width, ppu.Height))
+	for y := range frame {
+		row := img.Pix[y*img.Stride:]
+		for x, pixel := range frame[y] {
+			c := p.Colour(pixel)
+			row[x*4] = c.R
+			row[x*4+1] = c.G
+			row[x*4+2] = c.B
+			row[x*4+3] = c.A
+		}
+	}
+	return img
+}
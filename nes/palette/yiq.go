@@ -0,0 +1,118 @@
+package palette
+
+import (
+	"image/color"
+	"math"
+)
+
+// Settings adjust the palette produced by Generate, much like the picture controls of a television
+type Settings struct {
+	// Hue rotates every colour around the colour wheel, in degrees
+	Hue float64
+	// Saturation scales the strength of the colours. 0 is greyscale
+	Saturation float64
+	// Contrast scales the luma of every colour
+	Contrast float64
+	// Brightness is added to the luma of every colour. 0 is unchanged and 1 is white
+	Brightness float64
+}
+
+// DefaultSettings leave the decoded colours as they are
+var DefaultSettings = Settings{Saturation: 1, Contrast: 1}
+
+// Composite video levels of the 2C02 in volts, for the low and high halves of the colour wave at each of the four
+// luma levels. http://wiki.nesdev.com/w/index.php/NTSC_video
+var (
+	lowLevels  = [4]float64{0.350, 0.518, 0.962, 1.550}
+	highLevels = [4]float64{1.094, 1.506, 1.962, 1.962}
+)
+
+const (
+	blackLevel = 0.518
+	whiteLevel = 1.962
+
+	// The colour wave is a square wave with 12 phases, each of which lasts one master clock cycle
+	phases = 12
+	// Lines the phases up with the colour burst so that the hues come out where a television puts them
+	phaseOffset = 3.9
+	// Emphasis dims the signal during the phases of its colour
+	emphasisLevel = 0.746
+	// Televisions expect a gamma of 2.2 while the palette is tuned for displays of around 1.8
+	gamma = 2.2 / 1.8
+)
+
+// Generate builds a palette by simulating the composite signal the PPU outputs for each colour and decoding it as YIQ
+// the way an NTSC television does
+func Generate(s Settings) *Palette {
+	p := &Palette{}
+	hue := s.Hue * math.Pi / 180
+	for pixel := range p {
+		y, i, q := decode(uint16(pixel))
+		y = y*s.Contrast + s.Brightness
+		i *= s.Saturation * s.Contrast
+		q *= s.Saturation * s.Contrast
+		i, q = i*math.Cos(hue)-q*math.Sin(hue), i*math.Sin(hue)+q*math.Cos(hue)
+		p[pixel] = color.RGBA{
+			R: channel(y + 0.946882*i + 0.623557*q),
+			G: channel(y - 0.274788*i - 0.635691*q),
+			B: channel(y - 1.108545*i + 1.709007*q),
+			A: 0xFF,
+		}
+	}
+	return p
+}
+
+// decode averages the signal for a pixel over one period of the colour wave, returning its luma and chroma
+func decode(pixel uint16) (y, i, q float64) {
+	hue := int(pixel & 0x0F)
+	level := int(pixel>>4) & 0x03
+	emphasis := pixel >> 6
+	// Columns $xE and $xF are black
+	if hue >= 0x0E {
+		level = 1
+	}
+	low, high := lowLevels[level], highLevels[level]
+	// Column $x0 is a flat grey at the high level and $xD at the low level
+	switch {
+	case hue == 0x00:
+		low = high
+	case hue > 0x0C:
+		high = low
+	}
+
+	for phase := 0; phase < phases; phase++ {
+		signal := low
+		if inPhase(hue, phase) {
+			signal = high
+		}
+		// Red, green and blue emphasis are the phases of colours $x0, $x4 and $x8
+		if (emphasis&0x01 != 0 && inPhase(0, phase)) ||
+			(emphasis&0x02 != 0 && inPhase(4, phase)) ||
+			(emphasis&0x04 != 0 && inPhase(8, phase)) {
+			signal *= emphasisLevel
+		}
+		signal = (signal - blackLevel) / (whiteLevel - blackLevel)
+		angle := math.Pi * (float64(phase) + phaseOffset) / 6
+		y += signal
+		i += signal * math.Cos(angle)
+		q += signal * math.Sin(angle)
+	}
+	return y / phases, i / phases, q / phases
+}
+
+// inPhase returns true if the colour wave for a hue is high during a phase
+func inPhase(hue, phase int) bool {
+	return (hue+phase)%phases < phases/2
+}
+
+// channel gamma corrects a colour channel and converts it to 8 bits
+func channel(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	v = math.Pow(v, gamma)
+	if v >= 1 {
+		return 0xFF
+	}
+	return uint8(v * 0xFF)
+}
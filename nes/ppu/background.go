@@ -0,0 +1,145 @@
+package ppu
+
+// background holds the tile fetched for the next 8 pixels and the shift registers feeding the pixels being drawn.
+// http://wiki.nesdev.com/w/index.php/PPU_rendering
+type background struct {
+	nametable     uint8
+	attribute     uint8
+	patternLow    uint8
+	patternHigh   uint8
+	shiftLow      uint16
+	shiftHigh     uint16
+	attributeLow  uint16
+	attributeHigh uint16
+}
+
+const (
+	nametableBase uint16 = 0x2000
+	attributeBase uint16 = 0x23C0
+)
+
+// shiftBackground shifts the background registers by one pixel on the dots where the PPU is drawing or prefetching
+func (p *PPU) shiftBackground() {
+	if (p.dot >= 2 && p.dot <= horizontalCopyDot) || (p.dot >= prefetchStart+1 && p.dot <= prefetchEnd+1) {
+		b := &p.background
+		b.shiftLow <<= 1
+		b.shiftHigh <<= 1
+		b.attributeLow <<= 1
+		b.attributeHigh <<= 1
+	}
+}
+
+// fetchBackground performs the memory accesses of the background pipeline. Each tile takes 8 dots: the nametable
+// byte, the attribute byte, then the low and high pattern bytes, each read taking 2 dots. The fetched tile is loaded
+// into the shift registers at the start of the next tile. Two extra nametable reads at the end of the scanline are
+// never used but some mappers watch for them
+func (p *PPU) fetchBackground() {
+	dot := p.dot
+	if dot == horizontalCopyDot || dot == prefetchEnd+1 {
+		p.loadBackground()
+	}
+	if dot == prefetchEnd+1 || dot == prefetchEnd+3 {
+		p.read(nametableBase | p.v&0x0FFF)
+		return
+	}
+	if !(dot >= 1 && dot <= visibleDots) && !(dot >= prefetchStart && dot <= prefetchEnd) {
+		return
+	}
+
+	b := &p.background
+	switch (dot - 1) % 8 {
+	case 0:
+		if dot >= 9 && dot != prefetchStart {
+			p.loadBackground()
+		}
+		b.nametable = p.read(nametableBase | p.v&0x0FFF)
+	case 2:
+		attribute := p.read(attributeBase | p.v&0x0C00 | (p.v>>4)&0x38 | (p.v>>2)&0x07)
+		shift := (p.v>>4)&0x04 | p.v&0x02
+		b.attribute = (attribute >> shift) & 0x03
+	case 4:
+		b.patternLow = p.read(p.patternAddress())
+	case 6:
+		b.patternHigh = p.read(p.patternAddress() + 8)
+	case 7:
+		p.incrementX()
+	}
+}
+
+// patternAddress returns the address of the low plane of the current background tile row
+func (p *PPU) patternAddress() uint16 {
+	var table uint16
+	if p.ctrl&ctrlBackgroundTable != 0 {
+		table = 0x1000
+	}
+	return table | uint16(p.background.nametable)<<4 | (p.v>>12)&0x07
+}
+
+// loadBackground loads the fetched tile into the low 8 bits of the shift registers
+func (p *PPU) loadBackground() {
+	b := &p.background
+	b.shiftLow = b.shiftLow&0xFF00 | uint16(b.patternLow)
+	b.shiftHigh = b.shiftHigh&0xFF00 | uint16(b.patternHigh)
+	b.attributeLow &= 0xFF00
+	b.attributeHigh &= 0xFF00
+	if b.attribute&0x01 != 0 {
+		b.attributeLow |= 0x00FF
+	}
+	if b.attribute&0x02 != 0 {
+		b.attributeHigh |= 0x00FF
+	}
+}
+
+// backgroundPixel returns the palette RAM index of the background pixel at the fine X scroll, or 0 if it is
+// transparent
+func (p *PPU) backgroundPixel() uint8 {
+	b := &p.background
+	bit := uint(15 - p.x)
+	pixel := uint8(b.shiftLow>>bit&0x01) | uint8(b.shiftHigh>>bit&0x01)<<1
+	if pixel == 0 {
+		return 0
+	}
+	palette := uint8(b.attributeLow>>bit&0x01) | uint8(b.attributeHigh>>bit&0x01)<<1
+	return palette<<2 | pixel
+}
+
+// incrementX moves v to the next tile horizontally, switching horizontal nametable at the edge
+func (p *PPU) incrementX() {
+	if p.v&0x001F == 0x001F {
+		p.v &^= 0x001F
+		p.v ^= 0x0400
+	} else {
+		p.v++
+	}
+}
+
+// incrementY moves v down a pixel row, moving to the next tile row and switching vertical nametable after row 29. Rows
+// 30 and 31 are the attribute table and wrap without switching nametable
+func (p *PPU) incrementY() {
+	if p.v&0x7000 != 0x7000 {
+		p.v += 0x1000
+		return
+	}
+	p.v &^= 0x7000
+	y := (p.v & 0x03E0) >> 5
+	switch y {
+	case 29:
+		y = 0
+		p.v ^= 0x0800
+	case 31:
+		y = 0
+	default:
+		y++
+	}
+	p.v = p.v&^0x03E0 | y<<5
+}
+
+// copyX copies the horizontal scroll from t to v at the end of each scanline
+func (p *PPU) copyX() {
+	p.v = p.v&^0x041F | p.t&0x041F
+}
+
+// copyY copies the vertical scroll from t to v during the pre-render scanline
+func (p *PPU) copyY() {
+	p.v = p.v&^0x7BE0 | p.t&0x7BE0
+}
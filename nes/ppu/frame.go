@@ -0,0 +1,12 @@
+package ppu
+
+// Size of the picture in pixels
+const (
+	Width  = 256
+	Height = 240
+)
+
+// Frame is a rendered picture, indexed by row then column. Each pixel is an index into a 512 colour palette. The low 6
+// bits are the colour from palette RAM and the upper 3 bits are the emphasis bits from PPUMASK, the same layout as a
+// 512 entry .pal file
+type Frame [Height][Width]uint16
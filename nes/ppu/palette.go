@@ -0,0 +1,26 @@
+package ppu
+
+// Palette RAM holds 32 six bit colours, four palettes for the background followed by four for sprites. It is mapped at
+// $3F00-$3F1F and mirrored up to $3FFF. http://wiki.nesdev.com/w/index.php/PPU_palettes
+const (
+	paletteStart uint16 = 0x3F00
+	paletteSize         = 32
+)
+
+// paletteIndex returns the palette RAM entry for an address. Entry 0 of each sprite palette is shared with the
+// matching background palette, so $3F10, $3F14, $3F18 and $3F1C mirror $3F00, $3F04, $3F08 and $3F0C
+func paletteIndex(address uint16) uint16 {
+	index := address & 0x1F
+	if index&0x13 == 0x10 {
+		index &^= 0x10
+	}
+	return index
+}
+
+func (p *PPU) readPalette(address uint16) uint8 {
+	return p.palette[paletteIndex(address)]
+}
+
+func (p *PPU) writePalette(address uint16, data uint8) {
+	p.palette[paletteIndex(address)] = data & 0x3F
+}
@@ -0,0 +1,330 @@
+package ppu
+
+import (
+	"github.com/kevinwmiller/nesgo/nes/bus"
+	"github.com/kevinwmiller/nesgo/nes/clock"
+)
+
+// PPU is the Ricoh 2C02 picture processing unit. It renders a 256x240 picture by fetching tiles from the pattern tables
+// and nametables on its own bus, which is wired to the cartridge, and signals the CPU through NMI at the start of
+// vertical blank. http://wiki.nesdev.com/w/index.php/PPU
+type PPU struct {
+	// PPUCTRL, PPUMASK and the flags of PPUSTATUS
+	ctrl   uint8
+	mask   uint8
+	status uint8
+
+	// Internal scroll registers. http://wiki.nesdev.com/w/index.php/PPU_scrolling
+	//   v: the current VRAM address, 15 bits
+	//   t: the temporary VRAM address, the address of the top left onscreen tile
+	//   x: the fine X scroll, 3 bits
+	//   w: the first or second write toggle shared by PPUSCROLL and PPUADDR
+	v uint16
+	t uint16
+	x uint8
+	w bool
+
+	// PPUDATA reads from below the palettes are delayed by one read through this buffer
+	readBuffer uint8
+	// The PPU's internal data bus. Reads of write-only registers return whatever was last left on it
+	latch *bus.DecayingLatch
+
+	palette [paletteSize]uint8
+
+	oam     [oamSize]uint8
+	oamAddr uint8
+
+	background background
+	sprites    sprites
+
+	timing   timing
+	scanline int
+	dot      int
+	oddFrame bool
+	// The total number of dots since power on
+	dots uint64
+	// Reading PPUSTATUS on the dot before vblank starts stops the flag from being set for that frame
+	suppressVBlank bool
+
+	// Frames are rendered into back and swapped with front when vblank starts
+	front   *Frame
+	back    *Frame
+	frames  uint64
+	onFrame func(number uint64, frame *Frame)
+
+	nmi     func(asserted bool)
+	nmiLine bool
+
+	bus      bus.Device
+	observer addressObserver
+}
+
+// addressObserver is implemented by devices on the PPU bus that need to see the address the PPU drives even when it
+// isn't reading or writing, such as the MMC3 watching A12
+type addressObserver interface {
+	ObserveAddress(address uint16)
+}
+
+// Frame timing common to every region. http://wiki.nesdev.com/w/index.php/PPU_rendering
+const (
+	dotsPerScanline    = 341
+	visibleScanlines   = 240
+	visibleDots        = 256
+	prefetchStart      = 321
+	prefetchEnd        = 336
+	horizontalCopyDot  = 257
+	verticalCopyStart  = 280
+	verticalCopyEnd    = 304
+	oddFrameSkippedDot = 339
+
+	// The I/O latch loses its value after roughly 600ms, measured in dots
+	latchDecay = 3221590
+)
+
+// timing is the part of the frame timing that depends on the region. The pre-render scanline is always the last one
+type timing struct {
+	vblankScanline    int
+	preRenderScanline int
+	scanlinesPerFrame int
+	// NTSC PPUs skip a dot on odd frames to keep the colour subcarrier in phase
+	skipOddDot bool
+}
+
+// PAL PPUs have a vblank 50 scanlines longer than NTSC. Dendy clones instead add the extra scanlines before vblank, so
+// NMI handlers get the same time as on NTSC
+var timings = map[clock.Region]timing{
+	clock.NTSC:  {vblankScanline: 241, preRenderScanline: 261, scanlinesPerFrame: 262, skipOddDot: true},
+	clock.PAL:   {vblankScanline: 241, preRenderScanline: 311, scanlinesPerFrame: 312},
+	clock.Dendy: {vblankScanline: 291, preRenderScanline: 311, scanlinesPerFrame: 312},
+}
+
+// New returns an NTSC PPU in its power up state
+func New() *PPU {
+	return &PPU{
+		timing: timings[clock.NTSC],
+		latch:  bus.NewDecayingLatch(latchDecay),
+		front:  &Frame{},
+		back:   &Frame{},
+	}
+}
+
+// SetRegion switches the PPU to the frame timing of a region
+func (p *PPU) SetRegion(region clock.Region) {
+	p.timing = timings[region]
+}
+
+// ConnectBus connects the PPU to the device that decodes its address bus, $0000-$3EFF. Palette RAM at $3F00-$3FFF is
+// inside the PPU. Usually this is the cartridge
+func (p *PPU) ConnectBus(device bus.Device) {
+	p.bus = device
+	p.observer, _ = device.(addressObserver)
+}
+
+// ConnectNMI sets the function called when the PPU asserts or releases the CPU's NMI line
+func (p *PPU) ConnectNMI(nmi func(asserted bool)) {
+	p.nmi = nmi
+}
+
+// OnFrame sets a function to call with each frame as it completes. Frames are numbered from 0 at power on. The frame
+// is only valid until the function returns
+func (p *PPU) OnFrame(handler func(number uint64, frame *Frame)) {
+	p.onFrame = handler
+}
+
+// Reset puts the PPU in the state it is in after the reset button is pressed. The scroll, control and mask registers
+// are cleared but VRAM, palette RAM and the vblank flag are left alone
+func (p *PPU) Reset() {
+	p.ctrl = 0
+	p.mask = 0
+	p.w = false
+	p.t = 0
+	p.x = 0
+	p.readBuffer = 0
+	p.oddFrame = false
+	p.scanline = 0
+	p.dot = 0
+	p.updateNMI()
+}
+
+// Position returns the scanline and dot the PPU is on. The last scanline of the frame, 261 on NTSC, is the pre-render
+// scanline
+func (p *PPU) Position() (scanline, dot int) {
+	return p.scanline, p.dot
+}
+
+// Frame returns the last completed frame. It is only valid until the next frame completes
+func (p *PPU) Frame() *Frame {
+	return p.front
+}
+
+// Frames returns the number of frames completed since power on
+func (p *PPU) Frames() uint64 {
+	return p.frames
+}
+
+// Tick runs the PPU for a single dot
+func (p *PPU) Tick() {
+	p.dots++
+	switch {
+	case p.scanline < visibleScanlines || p.scanline == p.timing.preRenderScanline:
+		p.renderDot()
+	case p.scanline == p.timing.vblankScanline && p.dot == 1:
+		p.startVBlank()
+	}
+	p.advance()
+}
+
+// advance moves to the next dot. On NTSC with rendering enabled, the pre-render scanline of every odd frame is one dot
+// shorter
+func (p *PPU) advance() {
+	if p.scanline == p.timing.preRenderScanline && p.dot == oddFrameSkippedDot && p.oddFrame && p.timing.skipOddDot &&
+		p.renderingEnabled() {
+		p.dot++
+	}
+	p.dot++
+	if p.dot < dotsPerScanline {
+		return
+	}
+	p.dot = 0
+	p.scanline++
+	if p.scanline == p.timing.scanlinesPerFrame {
+		p.scanline = 0
+		p.oddFrame = !p.oddFrame
+	}
+}
+
+// renderDot runs a dot of a visible or pre-render scanline
+func (p *PPU) renderDot() {
+	if p.scanline == p.timing.preRenderScanline && p.dot == 1 {
+		p.status &^= statusVBlank | statusSprite0Hit | statusSpriteOverflow
+		p.updateNMI()
+	}
+	if !p.renderingEnabled() {
+		if p.scanline < visibleScanlines && p.dot >= 1 && p.dot <= visibleDots {
+			p.back[p.scanline][p.dot-1] = p.pixel(p.backdrop())
+		}
+		return
+	}
+
+	p.shiftBackground()
+	if p.scanline < visibleScanlines && p.dot >= 1 && p.dot <= visibleDots {
+		p.renderPixel()
+	}
+	p.fetchBackground()
+	if p.scanline < visibleScanlines {
+		p.evaluateSprites()
+	}
+	if p.dot >= spriteFetchStart && p.dot <= spriteFetchEnd {
+		p.fetchSprites()
+	}
+
+	switch {
+	case p.dot == visibleDots:
+		p.incrementY()
+	case p.dot == horizontalCopyDot:
+		p.copyX()
+	case p.scanline == p.timing.preRenderScanline && p.dot >= verticalCopyStart && p.dot <= verticalCopyEnd:
+		p.copyY()
+	}
+}
+
+// renderPixel draws the pixel for the current dot, choosing between the background and sprite pixels and checking for
+// a sprite 0 hit
+func (p *PPU) renderPixel() {
+	x := p.dot - 1
+	var background, sprite uint8
+	var behind, zero bool
+	if p.mask&maskBackground != 0 && (x >= 8 || p.mask&maskBackgroundLeft != 0) {
+		background = p.backgroundPixel()
+	}
+	if p.mask&maskSprites != 0 && (x >= 8 || p.mask&maskSpritesLeft != 0) {
+		sprite, behind, zero = p.spritePixel(x)
+	}
+
+	colour := background
+	if sprite != 0 && (background == 0 || !behind) {
+		colour = sprite
+	}
+	// Sprite 0 hits when an opaque sprite 0 pixel overlaps an opaque background pixel, except on the last column
+	if zero && background != 0 && x != Width-1 {
+		p.status |= statusSprite0Hit
+	}
+	p.back[p.scanline][x] = p.pixel(p.readPalette(paletteStart | uint16(colour)))
+}
+
+// backdrop returns the colour drawn while rendering is disabled. Normally it is the backdrop colour, but if the VRAM
+// address points into the palettes that colour is drawn instead
+func (p *PPU) backdrop() uint8 {
+	if p.v&0x3F00 == paletteStart {
+		return p.readPalette(p.v)
+	}
+	return p.readPalette(paletteStart)
+}
+
+// pixel applies the greyscale and emphasis bits of PPUMASK to a colour from palette RAM
+func (p *PPU) pixel(colour uint8) uint16 {
+	if p.mask&maskGreyscale != 0 {
+		colour &= 0x30
+	}
+	return uint16(p.mask&maskEmphasis)<<1 | uint16(colour&0x3F)
+}
+
+// startVBlank sets the vblank flag, which raises an NMI if enabled, and completes the frame
+func (p *PPU) startVBlank() {
+	if !p.suppressVBlank {
+		p.status |= statusVBlank
+		p.updateNMI()
+	}
+	p.suppressVBlank = false
+	p.front, p.back = p.back, p.front
+	p.frames++
+	if p.onFrame != nil {
+		p.onFrame(p.frames-1, p.front)
+	}
+}
+
+// renderingEnabled returns true if either the background or sprites are enabled. Turning both off stops the PPU from
+// fetching anything and frees VRAM for the CPU
+func (p *PPU) renderingEnabled() bool {
+	return p.mask&(maskBackground|maskSprites) != 0
+}
+
+// rendering returns true while the PPU is fetching and drawing, when rendering is enabled on a visible or pre-render
+// scanline. The PPU then owns VRAM and OAM and CPU accesses to them misbehave
+func (p *PPU) rendering() bool {
+	return p.renderingEnabled() && (p.scanline < visibleScanlines || p.scanline == p.timing.preRenderScanline)
+}
+
+// updateNMI drives the NMI line, which is asserted while vblank is set and NMI is enabled in PPUCTRL
+func (p *PPU) updateNMI() {
+	line := p.ctrl&ctrlNMI != 0 && p.status&statusVBlank != 0
+	if line != p.nmiLine {
+		p.nmiLine = line
+		if p.nmi != nil {
+			p.nmi(line)
+		}
+	}
+}
+
+// read reads from the PPU bus
+func (p *PPU) read(address uint16) uint8 {
+	if p.bus == nil {
+		return 0
+	}
+	return p.bus.Read(address & 0x3FFF)
+}
+
+// write writes to the PPU bus
+func (p *PPU) write(address uint16, data uint8) {
+	if p.bus == nil {
+		return
+	}
+	p.bus.Write(address&0x3FFF, data)
+}
+
+// setAddress lets devices on the bus see the VRAM address change outside of a read or write
+func (p *PPU) setAddress(address uint16) {
+	if p.observer != nil {
+		p.observer.ObserveAddress(address & 0x3FFF)
+	}
+}
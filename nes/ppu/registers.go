@@ -0,0 +1,139 @@
+package ppu
+
+// PPUCTRL ($2000) bits
+const (
+	ctrlNametable       uint8 = 0x03
+	ctrlIncrement32     uint8 = 0x04
+	ctrlSpriteTable     uint8 = 0x08
+	ctrlBackgroundTable uint8 = 0x10
+	ctrlSprite8x16      uint8 = 0x20
+	ctrlNMI             uint8 = 0x80
+)
+
+// PPUMASK ($2001) bits
+const (
+	maskGreyscale      uint8 = 0x01
+	maskBackgroundLeft uint8 = 0x02
+	maskSpritesLeft    uint8 = 0x04
+	maskBackground     uint8 = 0x08
+	maskSprites        uint8 = 0x10
+	maskEmphasis       uint8 = 0xE0
+)
+
+// PPUSTATUS ($2002) bits. The low 5 bits are not driven and read back the I/O latch
+const (
+	statusSpriteOverflow uint8 = 0x20
+	statusSprite0Hit     uint8 = 0x40
+	statusVBlank         uint8 = 0x80
+	statusDriven         uint8 = 0xE0
+)
+
+// Register numbers, the low 3 bits of $2000-$2007
+const (
+	ppuCtrl    = 0
+	ppuMask    = 1
+	ppuStatus  = 2
+	oamAddress = 3
+	oamData    = 4
+	ppuScroll  = 5
+	ppuAddress = 6
+	ppuData    = 7
+)
+
+// Read reads one of the PPU registers at $2000-$2007. Write-only registers return the I/O latch
+func (p *PPU) Read(address uint16) uint8 {
+	switch address & 0x07 {
+	case ppuStatus:
+		p.latch.Drive(p.status, statusDriven, p.dots)
+		p.status &^= statusVBlank
+		p.w = false
+		p.updateNMI()
+		// Reading on the dot before the flag is set stops it from being set at all
+		if p.scanline == p.timing.vblankScanline && p.dot == 0 {
+			p.suppressVBlank = true
+		}
+	case oamData:
+		p.latch.Drive(p.readOAM(), 0xFF, p.dots)
+	case ppuData:
+		p.readData()
+	}
+	return p.latch.Value(p.dots)
+}
+
+// Write writes one of the PPU registers at $2000-$2007
+func (p *PPU) Write(address uint16, data uint8) {
+	p.latch.Drive(data, 0xFF, p.dots)
+	switch address & 0x07 {
+	case ppuCtrl:
+		p.ctrl = data
+		p.t = p.t&^0x0C00 | uint16(data&ctrlNametable)<<10
+		p.updateNMI()
+	case ppuMask:
+		p.mask = data
+	case oamAddress:
+		p.oamAddr = data
+	case oamData:
+		p.writeOAM(data)
+	case ppuScroll:
+		if !p.w {
+			p.t = p.t&^0x001F | uint16(data>>3)
+			p.x = data & 0x07
+		} else {
+			p.t = p.t&^0x73E0 | uint16(data&0x07)<<12 | uint16(data&0xF8)<<2
+		}
+		p.w = !p.w
+	case ppuAddress:
+		if !p.w {
+			p.t = p.t&0x00FF | uint16(data&0x3F)<<8
+		} else {
+			p.t = p.t&0xFF00 | uint16(data)
+			p.v = p.t
+			p.setAddress(p.v)
+		}
+		p.w = !p.w
+	case ppuData:
+		p.writeData(data)
+	}
+}
+
+// readData reads PPUDATA. Reads below the palettes return the read buffer and refill it, so the data arrives one read
+// late. Palette reads return immediately but still refill the buffer with the nametable byte underneath
+func (p *PPU) readData() {
+	address := p.v & 0x3FFF
+	if address >= paletteStart {
+		// Palette entries are 6 bits. The upper 2 bits come from the latch
+		p.latch.Drive(p.readPalette(address), 0x3F, p.dots)
+		p.readBuffer = p.read(address - 0x1000)
+	} else {
+		p.latch.Drive(p.readBuffer, 0xFF, p.dots)
+		p.readBuffer = p.read(address)
+	}
+	p.incrementAddress()
+}
+
+func (p *PPU) writeData(data uint8) {
+	address := p.v & 0x3FFF
+	if address >= paletteStart {
+		p.writePalette(address, data)
+	} else {
+		p.write(address, data)
+	}
+	p.incrementAddress()
+}
+
+// incrementAddress moves v on after a PPUDATA access. While rendering, the access instead bumps both the coarse X and
+// Y scroll as if the PPU had fetched a tile
+func (p *PPU) incrementAddress() {
+	if p.rendering() {
+		p.incrementX()
+		p.incrementY()
+		return
+	}
+	if p.ctrl&ctrlIncrement32 != 0 {
+		p.v += 32
+	} else {
+		p.v++
+	}
+	p.v &= 0x7FFF
+	p.setAddress(p.v)
+}
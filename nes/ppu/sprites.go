@@ -0,0 +1,249 @@
+package ppu
+
+// Object attribute memory holds 64 sprites of 4 bytes each: Y position, tile index, attributes and X position.
+// http://wiki.nesdev.com/w/index.php/PPU_OAM
+const (
+	oamSize          = 256
+	secondaryOAMSize = 32
+	spritesPerLine   = 8
+
+	secondaryClearEnd     = 64
+	spriteEvaluationStart = 65
+	spriteEvaluationEnd   = visibleDots
+	spriteFetchStart      = 257
+	spriteFetchEnd        = 320
+)
+
+// Sprite attribute bits. Bits 2-4 are not implemented and always read back as 0
+const (
+	attributePalette uint8 = 0x03
+	attributeBehind  uint8 = 0x20
+	attributeFlipH   uint8 = 0x40
+	attributeFlipV   uint8 = 0x80
+	attributeMask    uint8 = 0xE3
+)
+
+// sprites holds the state of sprite evaluation for the next scanline and the sprites fetched for the current one.
+// http://wiki.nesdev.com/w/index.php/PPU_sprite_evaluation
+type sprites struct {
+	secondary [secondaryOAMSize]uint8
+	// The byte last read from OAM or secondary OAM. OAMDATA reads return it while rendering
+	latch uint8
+
+	// Evaluation state. found is the number of sprites copied to secondary OAM, copied is the number of bytes of the
+	// current sprite copied so far and done is set once every sprite has been looked at
+	found   int
+	copied  int
+	done    bool
+	hasZero bool
+
+	// The sprites fetched for the scanline being drawn
+	count       int
+	zero        bool
+	x           [spritesPerLine]uint8
+	attributes  [spritesPerLine]uint8
+	patternLow  [spritesPerLine]uint8
+	patternHigh [spritesPerLine]uint8
+}
+
+// spriteHeight returns 8 or 16 depending on the sprite size selected in PPUCTRL
+func (p *PPU) spriteHeight() int {
+	if p.ctrl&ctrlSprite8x16 != 0 {
+		return 16
+	}
+	return 8
+}
+
+// inRange returns true if a sprite with the Y position y covers the next scanline
+func (p *PPU) inRange(y uint8) bool {
+	row := p.scanline - int(y)
+	return row >= 0 && row < p.spriteHeight()
+}
+
+// evaluateSprites runs one dot of sprite evaluation, which finds the first 8 sprites on the next scanline. Secondary
+// OAM is cleared during dots 1-64 and filled during dots 65-256. Odd dots read OAM and even dots write secondary OAM
+func (p *PPU) evaluateSprites() {
+	s := &p.sprites
+	switch {
+	case p.dot == 0:
+	case p.dot <= secondaryClearEnd:
+		s.latch = 0xFF
+		if p.dot%2 == 0 {
+			s.secondary[p.dot/2-1] = s.latch
+		}
+	case p.dot <= spriteEvaluationEnd:
+		if p.dot == spriteEvaluationStart {
+			s.found = 0
+			s.copied = 0
+			s.done = false
+			s.hasZero = false
+		}
+		if p.dot%2 == 1 {
+			s.latch = p.oam[p.oamAddr]
+		} else {
+			p.evaluateSprite()
+		}
+	}
+}
+
+// evaluateSprite handles the write half of an evaluation step using the byte read on the previous dot
+func (p *PPU) evaluateSprite() {
+	s := &p.sprites
+	switch {
+	case s.done:
+		p.oamAddr += 4
+	case s.found < spritesPerLine:
+		s.secondary[s.found*4+s.copied] = s.latch
+		if s.copied == 0 {
+			if !p.inRange(s.latch) {
+				p.nextSprite(0)
+				return
+			}
+			// The first sprite evaluated is the one that can trigger a sprite 0 hit
+			if p.dot == spriteEvaluationStart+1 {
+				s.hasZero = true
+			}
+		}
+		s.copied++
+		p.oamAddr++
+		if s.copied == 4 {
+			s.copied = 0
+			s.found++
+			if p.oamAddr == 0 {
+				s.done = true
+			}
+		}
+	default:
+		// With secondary OAM full, the PPU keeps checking Y positions for the overflow flag. A hardware bug increments
+		// the byte offset along with the sprite index, so it checks tile, attribute and X bytes as if they were Y
+		if p.inRange(s.latch) {
+			p.status |= statusSpriteOverflow
+			s.done = true
+			return
+		}
+		p.nextSprite((p.oamAddr + 1) & 0x03)
+	}
+}
+
+// nextSprite moves evaluation on to the next sprite in OAM, at byte offset within it
+func (p *PPU) nextSprite(offset uint8) {
+	next := uint16(p.oamAddr&^0x03) + 4
+	if next > 0xFF {
+		p.sprites.done = true
+	}
+	p.oamAddr = uint8(next) | offset
+}
+
+// fetchSprites runs one dot of the sprite fetches for the next scanline. Each of the 8 slots takes 8 dots: two garbage
+// nametable reads followed by the low and high pattern bytes. Unused slots fetch tile $FF
+func (p *PPU) fetchSprites() {
+	s := &p.sprites
+	p.oamAddr = 0
+	slot := (p.dot - spriteFetchStart) / 8
+	phase := (p.dot - spriteFetchStart) % 8
+	if p.dot == spriteFetchStart {
+		s.count = s.found
+		s.zero = s.hasZero
+		if p.scanline == p.timing.preRenderScanline {
+			s.count = 0
+		}
+	}
+	if phase < 4 {
+		s.latch = s.secondary[slot*4+phase]
+	}
+
+	y, tile, attributes, x := uint8(0xFF), uint8(0xFF), uint8(0xFF), uint8(0xFF)
+	if slot < s.count {
+		y, tile, attributes, x = s.secondary[slot*4], s.secondary[slot*4+1], s.secondary[slot*4+2], s.secondary[slot*4+3]
+	}
+	switch phase {
+	case 0, 2:
+		p.read(nametableBase | p.v&0x0FFF)
+	case 4:
+		s.x[slot] = x
+		s.attributes[slot] = attributes
+		s.patternLow[slot] = p.spritePattern(slot, p.read(p.spriteAddress(y, tile, attributes)), attributes)
+	case 6:
+		s.patternHigh[slot] = p.spritePattern(slot, p.read(p.spriteAddress(y, tile, attributes)+8), attributes)
+	}
+}
+
+// spriteAddress returns the address of the low plane of the row of a sprite on the next scanline
+func (p *PPU) spriteAddress(y, tile, attributes uint8) uint16 {
+	height := p.spriteHeight()
+	row := (p.scanline - int(y)) & (height - 1)
+	if attributes&attributeFlipV != 0 {
+		row = height - 1 - row
+	}
+	var table uint16
+	if height == 16 {
+		table = uint16(tile&0x01) << 12
+		tile &^= 0x01
+		if row >= 8 {
+			tile++
+		}
+	} else if p.ctrl&ctrlSpriteTable != 0 {
+		table = 0x1000
+	}
+	return table | uint16(tile)<<4 | uint16(row&0x07)
+}
+
+// spritePattern returns the pattern byte to shift out for a slot, flipped horizontally if needed. Unused slots are
+// transparent even though they still fetch
+func (p *PPU) spritePattern(slot int, data, attributes uint8) uint8 {
+	if slot >= p.sprites.count {
+		return 0
+	}
+	if attributes&attributeFlipH != 0 {
+		data = reverse(data)
+	}
+	return data
+}
+
+// spritePixel returns the palette RAM index of the frontmost opaque sprite pixel at x, whether it is behind the
+// background and whether it belongs to sprite 0. The index is 0 if no sprite is opaque there
+func (p *PPU) spritePixel(x int) (colour uint8, behind, zero bool) {
+	s := &p.sprites
+	for i := 0; i < s.count; i++ {
+		offset := x - int(s.x[i])
+		if offset < 0 || offset > 7 {
+			continue
+		}
+		bit := uint(7 - offset)
+		pixel := s.patternLow[i]>>bit&0x01 | (s.patternHigh[i]>>bit&0x01)<<1
+		if pixel == 0 {
+			continue
+		}
+		attributes := s.attributes[i]
+		return 0x10 | (attributes&attributePalette)<<2 | pixel, attributes&attributeBehind != 0, i == 0 && s.zero
+	}
+	return 0, false, false
+}
+
+// readOAM reads OAMDATA. While rendering, the read returns whatever sprite evaluation or fetching is looking at
+func (p *PPU) readOAM() uint8 {
+	if p.rendering() {
+		return p.sprites.latch
+	}
+	return p.oam[p.oamAddr]
+}
+
+// writeOAM writes OAMDATA. Writes while rendering are ignored but bump the sprite index of OAMADDR
+func (p *PPU) writeOAM(data uint8) {
+	if p.rendering() {
+		p.oamAddr += 4
+		return
+	}
+	if p.oamAddr&0x03 == 2 {
+		data &= attributeMask
+	}
+	p.oam[p.oamAddr] = data
+	p.oamAddr++
+}
+
+// reverse reverses the bits of a byte
+func reverse(b uint8) uint8 {
+	b = b&0xF0>>4 | b&0x0F<<4
+	b = b&0xCC>>2 | b&0x33<<2
+	return b&0xAA>>1 | b&0x55<<1
+}
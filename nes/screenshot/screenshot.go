@@ -0,0 +1,88 @@
+// Package screenshot writes frames rendered by the PPU to PNG files
+package screenshot
+
+import (
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kevinwmiller/nesgo/nes/palette"
+	"github.com/kevinwmiller/nesgo/nes/ppu"
+)
+
+// Write encodes a frame as a PNG, converting it to RGB with pal
+func Write(w io.Writer, frame *ppu.Frame, pal *palette.Palette) error {
+	return png.Encode(w, pal.Image(frame))
+}
+
+// Save writes a frame to a PNG file at path, creating its directory if needed
+func Save(path string, frame *ppu.Frame, pal *palette.Palette) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, frame, pal); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Dumper writes a selection of frames to a numbered sequence of PNG files. Pass its Frame method to ppu.OnFrame
+type Dumper struct {
+	pattern string
+	palette *palette.Palette
+	every   uint64
+	first   uint64
+	last    uint64
+	err     error
+}
+
+// NewDumper returns a Dumper that writes every frame. pattern is a format string for the file names that is given
+// the frame number, such as "frames/%06d.png"
+func NewDumper(pattern string, pal *palette.Palette) *Dumper {
+	return &Dumper{
+		pattern: pattern,
+		palette: pal,
+		every:   1,
+		last:    ^uint64(0),
+	}
+}
+
+// Every only dumps every nth frame, counting from the start of the range
+func (d *Dumper) Every(n uint64) {
+	if n == 0 {
+		n = 1
+	}
+	d.every = n
+}
+
+// Range only dumps frames first to last inclusive
+func (d *Dumper) Range(first, last uint64) {
+	d.first = first
+	d.last = last
+}
+
+// Done returns true once every frame in the range has gone by
+func (d *Dumper) Done(number uint64) bool {
+	return number >= d.last
+}
+
+// Frame writes the frame if it is one of the selected frames. After an error no more frames are written and the error
+// can be checked with Err
+func (d *Dumper) Frame(number uint64, frame *ppu.Frame) {
+	if d.err != nil || number < d.first || number > d.last || (number-d.first)%d.every != 0 {
+		return
+	}
+	d.err = Save(fmt.Sprintf(d.pattern, number), frame, d.palette)
+}
+
+// Err returns the error that stopped the dump, if any
+func (d *Dumper) Err() error {
+	return d.err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kevinwmiller/nesgo/nes/audio"
+	"github.com/kevinwmiller/nesgo/nes/cartridge"
+	"github.com/kevinwmiller/nesgo/nes/ppu"
+)
+
+// nsfRecorder renders the tracks of an NSF to WAV files
+type nsfRecorder struct {
+	nsf  *cartridge.NSF
+	rate int
+	// The length and fade of tracks that don't give their own
+	length time.Duration
+	fade   time.Duration
+
+	interrupt <-chan struct{}
+}
+
+// recordSoundtrack records every track in the playlist to dir, naming each file after its place in the playlist and
+// its title
+func (r *nsfRecorder) recordSoundtrack(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, track := range r.nsf.Playlist {
+		if interrupted(r.interrupt) {
+			return nil
+		}
+		name := fmt.Sprintf("%02d", i+1)
+		if title := r.nsf.Tracks[track].Title; title != "" {
+			name += " " + fileName(title)
+		}
+		if err := r.recordTrack(track, filepath.Join(dir, name+".wav")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordTrack records one track, counting from 0, to a WAV file at path. Recording stops once the track has faded out
+func (r *nsfRecorder) recordTrack(track int, path string) error {
+	length, fade := r.nsf.Tracks[track].Length, r.nsf.Tracks[track].Fade
+	if length < 0 {
+		length = r.length
+	}
+	if fade < 0 {
+		fade = r.fade
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	wav, err := audio.NewWAVWriter(f, r.rate)
+	if err != nil {
+		return err
+	}
+	end := audio.NewFade(r.samples(length), r.samples(fade), wav)
+
+	nes := newConsole(r.nsf.Cartridge(track, regionFor(r.nsf.Timing).CPUFrequency()))
+	nes.record(r.rate, end)
+	nes.video.OnFrame(func(uint64, *ppu.Frame) {
+		if end.Done() || interrupted(r.interrupt) {
+			nes.clock.Stop()
+		}
+	})
+	nes.powerUp()
+	nes.clock.Start()
+	return wav.Close()
+}
+
+// samples converts a duration into a number of samples at the recording rate
+func (r *nsfRecorder) samples(d time.Duration) uint64 {
+	return uint64(d.Seconds() * float64(r.rate))
+}
+
+// fileName replaces the characters in a track title that can't be used in file names
+func fileName(title string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, title)
+}